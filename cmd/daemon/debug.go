@@ -12,11 +12,24 @@ func displayMETAR(data types.METARresponse) error {
 	fmt.Println("  ObsTime..........", data.ObsTime)
 	fmt.Println("  ReportTime.......", data.ReportTime)
 	fmt.Println("  Metar Type.......", data.MetarType)
-	fmt.Println("  Temp.............", data.Temp)
-	fmt.Println("  Dewp.............", data.Dewp)
-	fmt.Println("  Wind dir.........", data.Wdir)
-	fmt.Println("  Wind speed.......", data.Wspd)
-	fmt.Println("  Visib............", data.Visib)
+	if data.Temp != nil {
+		fmt.Println("  Temp.............", *data.Temp)
+	}
+	if data.Dewp != nil {
+		fmt.Println("  Dewp.............", *data.Dewp)
+	}
+	if data.Wdir != nil {
+		fmt.Println("  Wind dir.........", *data.Wdir)
+	}
+	if data.Wspd != nil {
+		fmt.Println("  Wind speed.......", *data.Wspd)
+	}
+	if data.Wgst != nil {
+		fmt.Println("  Wind gust........", *data.Wgst)
+	}
+	if data.Visib != nil {
+		fmt.Println("  Visib............", *data.Visib)
+	}
 	fmt.Println("  Altimeter........", fmt.Sprintf("%.1f", data.Altim))
 	if data.WxString != "" {
 		fmt.Println("  + Wx string......", data.WxString)
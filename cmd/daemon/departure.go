@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/category"
+	"github.com/house-holder/pilot-bar/internal/forecast"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// DepartureWindow is how far either side of the planned time is checked
+const DepartureWindow = 30 * time.Minute
+
+// parseDeparture accepts HHMM (UTC, next occurrence) or RFC3339
+func parseDeparture(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	hhmm, err := time.Parse("1504", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("departure %q: want HHMM (UTC) or RFC3339", value)
+	}
+	now = now.UTC()
+	t := time.Date(now.Year(), now.Month(), now.Day(), hhmm.Hour(), hhmm.Minute(), 0, 0, time.UTC)
+	if t.Before(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func checkDeparture(taf types.TAF, departure time.Time) *types.DepartureCheck {
+	resolved, ok := forecast.At(taf, departure)
+	if !ok {
+		return &types.DepartureCheck{
+			Time:     departure.Unix(),
+			Warnings: []string{"departure is outside the TAF valid period"},
+		}
+	}
+
+	check := &types.DepartureCheck{
+		Time:       departure.Unix(),
+		Prevailing: resolved.Prevailing.FltCat,
+		Worst:      resolved.Worst.FltCat,
+	}
+	if category.WorseThan(check.Prevailing, "MVFR") {
+		check.Warnings = append(check.Warnings,
+			fmt.Sprintf("%s forecast at departure", check.Prevailing))
+	}
+
+	overlaps := forecast.IFRDuring(taf, departure.Add(-DepartureWindow), departure.Add(DepartureWindow))
+	for _, overlap := range overlaps {
+		p := overlap.Period
+		label := p.Change
+		if p.Change == "PROB" {
+			label = fmt.Sprintf("PROB%d", p.Probability)
+		}
+		end := p.To
+		if p.Change == "BECMG" {
+			end = p.Becoming
+		}
+		check.Warnings = append(check.Warnings, fmt.Sprintf("%s %s-%s %s overlaps departure",
+			label,
+			time.Unix(p.From, 0).UTC().Format("1504Z"),
+			time.Unix(end, 0).UTC().Format("1504Z"),
+			overlap.FltCat))
+	}
+
+	if len(check.Warnings) > 0 {
+		slog.Warn("Departure", "time", departure.UTC().Format("021504Z"),
+			"warnings", fmt.Sprint(check.Warnings))
+	}
+	return check
+}
@@ -46,7 +46,7 @@ func (h *colorHandler) Handle(ctx context.Context, r slog.Record) error {
 	r.Attrs(func(a slog.Attr) bool {
 		key := strings.ToLower(a.Key)
 		switch key {
-		case "metar":
+		case "metar", "taf":
 			fmt.Fprintf(&b, "\n%s", a.Value.String())
 			return false
 		case "airport":
@@ -3,25 +3,45 @@ package main
 // comment added for no reason
 
 import (
+	"context"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/house-holder/pilot-bar/internal/config"
+	"github.com/house-holder/pilot-bar/internal/geo"
 	"github.com/spf13/pflag"
 )
 
 type Flags struct {
-	Airport *string
-	Debug   *bool
-	Info    *bool
-	Update  *bool
-	Verbose *bool
+	Airport  *string
+	Debug    *bool
+	Info     *bool
+	Once     *bool
+	Update   *bool
+	Verbose  *bool
+	Source   *string
+	BaseURL  *string
+	Fixtures *string
+	Strict   *bool // nil unless given, so the config decides
+	Depart   *string
 }
 
 func setupFlags() Flags {
 	info := pflag.BoolP("info", "i", false, "enable info logging")
 	debug := pflag.BoolP("debug", "d", false, "enable debug logging")
 	update := pflag.BoolP("update", "u", false, "force update cycle")
+	once := pflag.BoolP("once", "1", false, "run a single update cycle and exit")
+	source := pflag.StringP("source", "s", "", "weather source: awc or fixture (default from config)")
+	baseURL := pflag.String("base-url", "", "API base URL for --source awc, e.g. a mockawc server")
+	fixtures := pflag.String("fixtures", "", "fixture dir for --source fixture (default from config)")
+	strict := pflag.Bool("strict", false, "fail on unknown or malformed API fields (default from config)")
 	verbose := pflag.BoolP("verbose", "v", false, "enable verbose output")
 
+	depart := pflag.String("depart", "", "planned departure, HHMM (UTC) or RFC3339; checked against the TAF")
+
 	defaultID, err := resolveAirport()
 	if err != nil {
 		slog.Error("failed to resolve default airport", "error", err)
@@ -30,12 +50,21 @@ func setupFlags() Flags {
 	airport := pflag.StringP("airport", "a", defaultID, "target station ID")
 
 	pflag.Parse()
+	if !pflag.CommandLine.Changed("strict") {
+		strict = nil
+	}
 	return Flags{
-		Airport: airport,
-		Debug:   debug,
-		Info:    info,
-		Update:  update,
-		Verbose: verbose,
+		Airport:  airport,
+		Debug:    debug,
+		Info:     info,
+		Once:     once,
+		Update:   update,
+		Verbose:  verbose,
+		Source:   source,
+		BaseURL:  baseURL,
+		Fixtures: fixtures,
+		Strict:   strict,
+		Depart:   depart,
 	}
 }
 
@@ -43,7 +72,40 @@ func main() {
 	flags := setupFlags()
 	InitLogger(flags)
 
-	if err := Update(flags); err != nil {
-		slog.Error("Update", "error", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *flags.Depart != "" {
+		if _, err := parseDeparture(*flags.Depart, time.Now()); err != nil {
+			slog.Error("Flags", "error", err)
+			os.Exit(2)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("config, using defaults", "error", err)
+		cfg = config.Default()
+	}
+	if cfg.MagneticModel != "" {
+		if err := geo.UseModel(cfg.MagneticModel); err != nil {
+			slog.Error("config, using the built-in magnetic model", "error", err)
+		}
 	}
+
+	src, err := newSource(cfg.Source, flags)
+	if err != nil {
+		slog.Error("Source", "error", err)
+		os.Exit(1)
+	}
+
+	if *flags.Once {
+		if err := Update(ctx, src, cfg, flags); err != nil {
+			slog.Error("Update", "error", err)
+		}
+		return
+	}
+
+	slog.Info("Starting daemon", "airport", *flags.Airport)
+	runScheduler(ctx, buildJobs(src, cfg, flags))
 }
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/config"
+	"github.com/house-holder/pilot-bar/internal/fetch"
+)
+
+const (
+	// hourly METARs are observed :53-:56 and usually published a minute or
+	// two later, so the routine refresh lands just after that window
+	METARAlignMinute = 57
+	METARLateRetry   = 2 * time.Minute
+	METARLateAfter   = 55 * time.Minute
+	METARMissingAt   = 75 * time.Minute
+
+	SPECIInterval = 10 * time.Minute
+	RetryDelay    = 5 * time.Minute
+)
+
+// job refreshes one product; run returns when the job should next run
+type job struct {
+	name  string
+	first time.Time
+	run   func(ctx context.Context, now time.Time) time.Time
+}
+
+func runScheduler(ctx context.Context, jobs []job) {
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Go(func() { j.loop(ctx) })
+	}
+	wg.Wait()
+	slog.Info("Scheduler stopped")
+}
+
+func (j job) loop(ctx context.Context) {
+	next := j.first
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		next = j.run(ctx, time.Now())
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Info("Scheduled", "product", j.name, "next", next.UTC().Format("1504:05Z"))
+	}
+}
+
+func buildJobs(src fetch.Source, cfg *config.Config, flags Flags) []job {
+	cachedWX, err := readCachedWX(CachePath, flags)
+	if err != nil {
+		slog.Warn("unable to read cache, refreshing everything", "error", err)
+	}
+	d := newUpdateData(cachedWX, flags)
+	now := time.Now()
+
+	metarFirst := now
+	if !d.NeedsAnyUpdate(*flags.Update) {
+		metarFirst = nextMETARRun(now, metarResult{
+			obsEpoch: cachedWX.METAR.Reported.Epoch,
+			newObs:   true,
+			fltCat:   cachedWX.METAR.Category(),
+		}, d.intervalMETAR)
+	}
+
+	jobs := []job{
+		{
+			name:  "METAR",
+			first: metarFirst,
+			run: func(ctx context.Context, now time.Time) time.Time {
+				result, err := updateMETAR(ctx, src, cfg, flags)
+				if err != nil {
+					if ctx.Err() == nil {
+						slog.Error("METAR update", "error", err)
+					}
+					return now.Add(RetryDelay)
+				}
+				return nextMETARRun(now, result, d.intervalMETAR)
+			},
+		},
+	}
+
+	if cfg.Modules.TAF {
+		jobs = append(jobs, job{
+			name: "TAF",
+			// after the METAR job has had a chance to set up the cache
+			first: now.Add(5 * time.Second),
+			run: func(ctx context.Context, now time.Time) time.Time {
+				if err := updateTAF(ctx, src, cfg, flags); err != nil {
+					if ctx.Err() == nil {
+						slog.Error("TAF update", "error", err)
+					}
+					return now.Add(RetryDelay)
+				}
+				return now.Add(d.intervalTAF)
+			},
+		})
+	}
+
+	if cfg.Modules.PIREP {
+		jobs = append(jobs, job{
+			name:  "PIREP",
+			first: now.Add(10 * time.Second),
+			run: func(ctx context.Context, now time.Time) time.Time {
+				if err := updatePIREPs(ctx, src, cfg.PIREP, flags); err != nil {
+					if ctx.Err() == nil {
+						slog.Error("PIREP update", "error", err)
+					}
+					return now.Add(RetryDelay)
+				}
+				return now.Add(d.intervalPIREP)
+			},
+		})
+	}
+
+	if cfg.Modules.AFD {
+		jobs = append(jobs, job{
+			name:  "AFD",
+			first: now.Add(15 * time.Second),
+			run: func(ctx context.Context, now time.Time) time.Time {
+				if err := updateAFD(ctx, src, flags); err != nil {
+					if ctx.Err() == nil {
+						slog.Error("AFD update", "error", err)
+					}
+					return now.Add(RetryDelay)
+				}
+				return now.Add(d.intervalAFD)
+			},
+		})
+	}
+
+	if cfg.Modules.AIRMET {
+		jobs = append(jobs, job{
+			name:  "AIRMET",
+			first: now.Add(20 * time.Second),
+			run: func(ctx context.Context, now time.Time) time.Time {
+				if err := updateAdvisories(ctx, src, cfg.Advisory, flags); err != nil {
+					if ctx.Err() == nil {
+						slog.Error("AIRMET update", "error", err)
+					}
+					return now.Add(RetryDelay)
+				}
+				return now.Add(d.intervalAIRMET)
+			},
+		})
+	}
+	return jobs
+}
+
+// nextMETARRun aligns routine refreshes to the hourly observation, polls
+// briefly when the hourly looks late, and tightens the cycle when a SPECI
+// is likely
+func nextMETARRun(now time.Time, result metarResult, interval time.Duration) time.Time {
+	aligned := nextAligned(now, METARAlignMinute)
+	age := now.Sub(time.Unix(result.obsEpoch, 0))
+
+	switch {
+	case !result.newObs && age > METARLateAfter && age < METARMissingAt:
+		return now.Add(METARLateRetry)
+	case speciLikely(result.fltCat):
+		return earliest(now.Add(SPECIInterval), aligned)
+	default:
+		return earliest(now.Add(interval), aligned)
+	}
+}
+
+// speciLikely - special observations are issued as conditions cross
+// category thresholds, which happens most often once already below VFR
+func speciLikely(fltCat string) bool {
+	return fltCat == "IFR" || fltCat == "LIFR"
+}
+
+func nextAligned(now time.Time, minute int) time.Time {
+	next := now.Truncate(time.Hour).Add(time.Duration(minute) * time.Minute)
+	if !next.After(now) {
+		next = next.Add(time.Hour)
+	}
+	return next
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
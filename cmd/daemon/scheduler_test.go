@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextMETARRun(t *testing.T) {
+	at := func(hour, minute, second int) time.Time {
+		return time.Date(2025, 10, 25, hour, minute, second, 0, time.UTC)
+	}
+	obs := func(hour, minute int) int64 { return at(hour, minute, 0).Unix() }
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		result   metarResult
+		interval time.Duration
+		want     time.Time
+	}{
+		{"aligned to :57", at(14, 10, 0), metarResult{obs(13, 53), true, "VFR"}, time.Hour, at(14, 57, 0)},
+		{"just before :57", at(14, 56, 59), metarResult{obs(13, 53), true, "VFR"}, time.Hour, at(14, 57, 0)},
+		{"at :57 rolls to next hour", at(14, 57, 0), metarResult{obs(14, 53), true, "VFR"}, time.Hour, at(15, 57, 0)},
+		{"past :57", at(14, 58, 30), metarResult{obs(14, 53), true, "MVFR"}, time.Hour, at(15, 57, 0)},
+		{"short interval", at(14, 10, 0), metarResult{obs(13, 53), true, "VFR"}, 15 * time.Minute, at(14, 25, 0)},
+
+		{"SPECI likely", at(14, 20, 0), metarResult{obs(13, 53), true, "IFR"}, time.Hour, at(14, 30, 0)},
+		{"SPECI capped at :57", at(14, 50, 0), metarResult{obs(13, 53), true, "LIFR"}, time.Hour, at(14, 57, 0)},
+		{"SPECI across the hour", at(14, 57, 30), metarResult{obs(14, 53), true, "IFR"}, time.Hour, at(15, 7, 30)},
+		{"SPECI after midnight", at(23, 58, 0), metarResult{obs(23, 53), true, "IFR"}, time.Hour, at(24, 8, 0)},
+
+		{"late hourly", at(14, 58, 0), metarResult{obs(13, 53), false, "VFR"}, time.Hour, at(15, 0, 0)},
+		{"not yet late", at(14, 40, 0), metarResult{obs(13, 53), false, "VFR"}, time.Hour, at(14, 57, 0)},
+		{"missing hourly", at(15, 10, 0), metarResult{obs(13, 53), false, "VFR"}, time.Hour, at(15, 57, 0)},
+	}
+	for _, tt := range tests {
+		if got := nextMETARRun(tt.now, tt.result, tt.interval); !got.Equal(tt.want) {
+			t.Errorf("%s: next = %s, want %s", tt.name, got.Format("02 1504:05Z"), tt.want.Format("02 1504:05Z"))
+		}
+	}
+}
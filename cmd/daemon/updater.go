@@ -1,34 +1,110 @@
 package main
 
 import (
-	"encoding/json"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/house-holder/pilot-bar/internal/cache"
+	"github.com/house-holder/pilot-bar/internal/category"
+	"github.com/house-holder/pilot-bar/internal/config"
+	"github.com/house-holder/pilot-bar/internal/derive"
 	"github.com/house-holder/pilot-bar/internal/fetch"
+	"github.com/house-holder/pilot-bar/internal/geo"
+	"github.com/house-holder/pilot-bar/internal/minimums"
 	"github.com/house-holder/pilot-bar/internal/parse"
+	"github.com/house-holder/pilot-bar/internal/runways"
 	"github.com/house-holder/pilot-bar/pkg/types"
 )
 
+// CachePath is where the airport's weather is kept for the bar
+var CachePath = cache.DefaultPath()
+
 const (
-	MaxTries  = 5
-	CachePath = "./testdata/currentWX.json"
-
-	// NOTE: seconds vals below, 1min for testing - must edit for prod
-	UpdateInterval = 60
-	IntervalMETAR  = 60
-	IntervalTAF    = 60
-	IntervalAFD    = 60
+	MaxTries = 5
+	// overall budget for one product fetch, retries included
+	FetchDeadline = 2 * time.Minute
+
+	IntervalMETAR  = time.Hour
+	IntervalTAF    = 30 * time.Minute
+	IntervalAFD    = time.Hour
+	IntervalPIREP  = 15 * time.Minute
+	IntervalAIRMET = 15 * time.Minute
 )
 
 type UpdateData struct {
-	cached        types.Airport
-	requested     string
-	now           int64
-	intervalMETAR int64
-	intervalTAF   int64
-	intervalAFD   int64
+	cached         types.Airport
+	requested      string
+	now            int64
+	intervalMETAR  time.Duration
+	intervalTAF    time.Duration
+	intervalAFD    time.Duration
+	intervalPIREP  time.Duration
+	intervalAIRMET time.Duration
+}
+
+// newSource picks the weather backend; flags override the config
+func newSource(cfg config.SourceCfg, flags Flags) (fetch.Source, error) {
+	kind, baseURL, dir := cfg.Kind, cfg.BaseURL, cfg.FixtureDir
+	if *flags.Source != "" {
+		kind = *flags.Source
+	}
+	if *flags.BaseURL != "" {
+		baseURL = *flags.BaseURL
+	}
+	if *flags.Fixtures != "" {
+		dir = *flags.Fixtures
+	}
+	strict := cfg.Strict
+	if flags.Strict != nil {
+		strict = *flags.Strict
+	}
+
+	switch kind {
+	case fetch.SourceAWC, "":
+		client := fetch.NewClient()
+		client.MaxAttempts = MaxTries
+		client.Deadline = FetchDeadline
+		client.Strict = strict
+		if baseURL != "" {
+			client.BaseURL = baseURL
+		}
+		if cfg.NWSBaseURL != "" {
+			client.NWSBaseURL = cfg.NWSBaseURL
+		}
+		return client, nil
+	case fetch.SourceFixture:
+		slog.Info("Using fixture source", "dir", dir)
+		fixture, err := fetch.NewFixture(dir)
+		if err != nil {
+			return nil, err
+		}
+		fixture.Strict = strict
+		return fixture, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q", kind)
+	}
+}
+
+func newUpdateData(cached types.Airport, flags Flags) *UpdateData {
+	return &UpdateData{
+		cached:         cached,
+		requested:      *flags.Airport,
+		now:            time.Now().Unix(),
+		intervalMETAR:  IntervalMETAR,
+		intervalTAF:    IntervalTAF,
+		intervalAFD:    IntervalAFD,
+		intervalPIREP:  IntervalPIREP,
+		intervalAIRMET: IntervalAIRMET,
+	}
 }
 
 func (d *UpdateData) ICAOChanged() bool {
@@ -40,7 +116,7 @@ func (d *UpdateData) METAREmpty() bool {
 }
 
 func (d *UpdateData) TimeExpired() bool {
-	return d.now-d.cached.LastUpdateEpoch > UpdateInterval
+	return d.now-d.cached.LastUpdateEpoch > int64(d.intervalMETAR.Seconds())
 }
 
 func (d *UpdateData) NeedsAnyUpdate(force bool) bool {
@@ -56,28 +132,54 @@ func (d *UpdateData) NeedsAnyUpdate(force bool) bool {
 	return false
 }
 
-func Update(flags Flags) error {
+// Update runs a single update cycle, skipping it if the cache is current
+func Update(ctx context.Context, src fetch.Source, cfg *config.Config, flags Flags) error {
 	cachedWX, err := readCachedWX(CachePath, flags)
 	if err != nil {
 		return err
 	}
 
-	d := &UpdateData{
-		cached:        cachedWX,
-		requested:     *flags.Airport,
-		now:           time.Now().Unix(),
-		intervalMETAR: IntervalMETAR,
-		intervalTAF:   IntervalTAF,
-		intervalAFD:   IntervalAFD,
+	if !newUpdateData(cachedWX, flags).NeedsAnyUpdate(*flags.Update) {
+		return nil
 	}
 
-	if !d.NeedsAnyUpdate(*flags.Update) {
-		return nil
+	if _, err := updateMETAR(ctx, src, cfg, flags); err != nil {
+		return err
 	}
 
-	APImetar, err := fetch.GetMETAR(*flags.Airport, MaxTries)
+	// the remaining products are independent of each other
+	var errs []error
+	if cfg.Modules.TAF {
+		errs = append(errs, updateTAF(ctx, src, cfg, flags))
+	}
+	if cfg.Modules.PIREP {
+		errs = append(errs, updatePIREPs(ctx, src, cfg.PIREP, flags))
+	}
+	if cfg.Modules.AFD {
+		errs = append(errs, updateAFD(ctx, src, flags))
+	}
+	if cfg.Modules.AIRMET {
+		errs = append(errs, updateAdvisories(ctx, src, cfg.Advisory, flags))
+	}
+	return errors.Join(errs...)
+}
+
+type metarResult struct {
+	obsEpoch int64
+	newObs   bool
+	fltCat   string
+}
+
+func updateMETAR(ctx context.Context, src fetch.Source, cfg *config.Config, flags Flags) (metarResult, error) {
+	rules, err := category.Lookup(cfg.CategoryRules)
 	if err != nil {
-		return err
+		slog.Warn("config, using FAA categories", "error", err)
+		rules = category.FAA
+	}
+
+	APImetar, err := src.GetMETAR(ctx, *flags.Airport)
+	if err != nil {
+		return metarResult{}, err
 	}
 
 	if *flags.Verbose {
@@ -86,18 +188,346 @@ func Update(flags Flags) error {
 		slog.Debug("", "metar", APImetar.RawOb)
 	}
 
-	if err := parse.BuildInternalMETAR(&APImetar, &cachedWX.METAR); err != nil {
+	var result metarResult
+	err = modifyCache(flags, func(cachedWX *types.Airport) error {
+		var metar types.METAR
+		if err := parse.BuildInternalMETAR(&APImetar, &metar); err != nil {
+			return err
+		}
+		metar.FltCatDerived = rules.METAR(metar)
+
+		if cachedWX.ICAO != *flags.Airport {
+			cachedWX.TAF = types.TAF{}
+			cachedWX.PIREPs = types.PIREPArea{}
+			cachedWX.AFD = types.AFD{}
+			cachedWX.Advisories = types.AdvisoryArea{}
+		}
+		// station details come with every METAR, so a cache written
+		// without them (or by an older version) is filled in
+		cachedWX.ICAO = *flags.Airport
+		if APImetar.Name != "" {
+			cachedWX.Name = APImetar.Name
+		}
+		if APImetar.Lat != 0 || APImetar.Long != 0 {
+			cachedWX.Elevation = types.Feet(float64(APImetar.Elev) * 3.28084)
+			cachedWX.Lat = APImetar.Lat
+			cachedWX.Lon = APImetar.Long
+		}
+
+		result = metarResult{
+			obsEpoch: APImetar.ObsTime,
+			newObs:   cachedWX.METAR.Reported.Epoch != APImetar.ObsTime,
+			fltCat:   metar.Category(),
+		}
+
+		metar.Reported.Epoch = APImetar.ObsTime
+		cachedWX.MagVar = magneticVariation(*cachedWX, time.Unix(APImetar.ObsTime, 0))
+		if cachedWX.MagVar != nil && !metar.Wind.Calm && !metar.Wind.Variable {
+			mag := types.DegMag(math.Round(geo.TrueToMagnetic(float64(metar.Wind.Direction), *cachedWX.MagVar)))
+			if mag == 0 {
+				mag = 360
+			}
+			metar.Wind.DirectionMag = &mag
+		}
+		cachedWX.METAR = metar
+		cachedWX.Runways = runwayWinds(*cachedWX)
+		cachedWX.Derived = derive.Compute(cachedWX.Elevation, metar)
+		if d := cachedWX.Derived; d != nil && d.DensityAlt != nil && cfg.HighDensityAlt > 0 {
+			d.HighDA = *d.DensityAlt-cachedWX.Elevation >= types.Feet(cfg.HighDensityAlt)
+		}
+		cachedWX.LastUpdateEpoch = time.Now().Unix()
+		refreshMinimums(cachedWX, cfg.Minimums, time.Now())
+		return nil
+	})
+	return result, err
+}
+
+func updateTAF(ctx context.Context, src fetch.Source, cfg *config.Config, flags Flags) error {
+	APItaf, err := src.GetTAF(ctx, *flags.Airport)
+	if err != nil {
+		return err
+	}
+	slog.Debug("", "taf", APItaf.RawTAF)
+
+	var taf types.TAF
+	if err := parse.BuildInternalTAF(&APItaf, &taf); err != nil {
 		return err
 	}
+	crossCheckTAF(taf)
 
-	if d.ICAOChanged() {
-		cachedWX.ICAO = *flags.Airport
-		cachedWX.Elevation = types.Feet(float64(APImetar.Elev) * 3.28084)
+	return modifyCache(flags, func(cachedWX *types.Airport) error {
+		if cachedWX.ICAO != *flags.Airport {
+			return fmt.Errorf("TAF for %s, cache holds %s", *flags.Airport, cachedWX.ICAO)
+		}
+		cachedWX.TAF = taf
+		cachedWX.Departure = nil
+		if *flags.Depart != "" {
+			departure, err := parseDeparture(*flags.Depart, time.Now())
+			if err != nil {
+				return err
+			}
+			cachedWX.Departure = checkDeparture(taf, departure)
+		}
+		refreshMinimums(cachedWX, cfg.Minimums, time.Now())
+		return nil
+	})
+}
+
+// magneticVariation is the declination at the airport from the embedded
+// World Magnetic Model, nil until its position is known
+func magneticVariation(ap types.Airport, at time.Time) *float64 {
+	if ap.Lat == 0 && ap.Lon == 0 {
+		return nil
 	}
+	altitudeKm := float64(ap.Elevation) * 0.0003048
+	declination, err := geo.Declination(ap.Lat, ap.Lon, altitudeKm, at)
+	if err != nil {
+		slog.Error("Magnetic variation", "error", err)
+		return nil
+	}
+	if name, from, to, _ := geo.MagneticModel(); float64(at.Year()) < from || float64(at.Year()) >= to {
+		modelWarning.Do(func() {
+			slog.Warn("Magnetic variation extrapolated, set magneticModel to a current WMM.COF",
+				"model", name, "valid", fmt.Sprintf("%.0f-%.0f", from, to))
+		})
+	}
+	return &declination
+}
 
-	cachedWX.LastUpdateEpoch = time.Now().Unix()
-	cachedWX.METAR.Reported.Epoch = int64(APImetar.ObsTime)
+var modelWarning sync.Once
 
+// runwayWinds resolves the METAR wind onto the airport's runways, if it's
+// in the runway data
+func runwayWinds(ap types.Airport) []types.RunwayWind {
+	list, err := runways.For(ap.ICAO)
+	if err != nil {
+		slog.Error("Runways", "error", err)
+		return nil
+	}
+	if len(list) == 0 {
+		slog.Warn("No runway data; regenerate internal/runways to add it", "icao", ap.ICAO)
+		return nil
+	}
+	declination := 0.0
+	if ap.MagVar != nil {
+		declination = *ap.MagVar
+	}
+	return runways.Components(list, ap.METAR.Wind, declination)
+}
+
+// refreshMinimums re-evaluates the personal minimums after a METAR or TAF
+func refreshMinimums(cachedWX *types.Airport, limits config.MinimumsCfg, now time.Time) {
+	if !minimums.Configured(limits) {
+		cachedWX.Minimums = nil
+		return
+	}
+	violations := minimums.Evaluate(limits, *cachedWX, now)
+	if len(violations) > 0 {
+		slog.Warn("Minimums", "violations", fmt.Sprint(violations))
+	}
+	cachedWX.Minimums = &types.MinimumsCheck{Time: now.Unix(), Violations: violations}
+}
+
+// updatePIREPs keeps the reports within the configured radius and altitude
+// band of the cached airport, nearest first
+func updatePIREPs(ctx context.Context, src fetch.Source, area config.PIREPCfg, flags Flags) error {
+	cachedWX, err := readCachedWX(CachePath, flags)
+	if err != nil {
+		return err
+	}
+	if cachedWX.ICAO != *flags.Airport || (cachedWX.Lat == 0 && cachedWX.Lon == 0) {
+		return fmt.Errorf("no position cached for %s yet", *flags.Airport)
+	}
+
+	APIpireps, err := src.GetPIREPs(ctx, fetch.PIREPQuery{
+		Lat:      cachedWX.Lat,
+		Lon:      cachedWX.Lon,
+		RadiusNM: area.RadiusNM,
+		AgeHours: area.AgeHours,
+	})
+	if err != nil {
+		return err
+	}
+
+	reports := make([]types.PIREP, 0, len(APIpireps))
+	for i := range APIpireps {
+		var pirep types.PIREP
+		if err := parse.BuildInternalPIREP(&APIpireps[i], &pirep); err != nil {
+			return err
+		}
+		pirep.DistanceNM = geo.DistanceNM(cachedWX.Lat, cachedWX.Lon, pirep.Lat, pirep.Lon)
+		pirep.Bearing = geo.Bearing(cachedWX.Lat, cachedWX.Lon, pirep.Lat, pirep.Lon)
+		if pirep.DistanceNM > area.RadiusNM || !withinAltitudes(pirep, area) {
+			continue
+		}
+		reports = append(reports, pirep)
+	}
+	slices.SortFunc(reports, func(a, b types.PIREP) int {
+		return cmp.Compare(a.DistanceNM, b.DistanceNM)
+	})
+	slog.Debug("PIREPs", "received", len(APIpireps), "kept", len(reports))
+
+	return modifyCache(flags, func(cachedWX *types.Airport) error {
+		if cachedWX.ICAO != *flags.Airport {
+			return fmt.Errorf("PIREPs for %s, cache holds %s", *flags.Airport, cachedWX.ICAO)
+		}
+		cachedWX.PIREPs = types.PIREPArea{
+			RadiusNM: area.RadiusNM,
+			MinAlt:   types.Feet(area.MinAlt),
+			MaxAlt:   types.Feet(area.MaxAlt),
+			Fetched:  time.Now().Unix(),
+			Reports:  reports,
+		}
+		return nil
+	})
+}
+
+// updateAFD stores the discussion for the airport's forecast office,
+// noting when a new issuance first appears
+func updateAFD(ctx context.Context, src fetch.Source, flags Flags) error {
+	cachedWX, err := readCachedWX(CachePath, flags)
+	if err != nil {
+		return err
+	}
+	if cachedWX.ICAO != *flags.Airport || (cachedWX.Lat == 0 && cachedWX.Lon == 0) {
+		return fmt.Errorf("no position cached for %s yet", *flags.Airport)
+	}
+
+	APIafd, err := src.GetAFD(ctx, cachedWX.Lat, cachedWX.Lon)
+	if err != nil {
+		return err
+	}
+
+	var afd types.AFD
+	if err := parse.BuildInternalAFD(&APIafd, &afd); err != nil {
+		return err
+	}
+
+	return modifyCache(flags, func(cachedWX *types.Airport) error {
+		if cachedWX.ICAO != *flags.Airport {
+			return fmt.Errorf("AFD for %s, cache holds %s", *flags.Airport, cachedWX.ICAO)
+		}
+		if cachedWX.AFD.ID == afd.ID {
+			afd.Received = cachedWX.AFD.Received
+		} else {
+			afd.Received = time.Now().Unix()
+			slog.Info("New AFD", "office", afd.Office,
+				"issued", time.Unix(afd.Issued, 0).UTC().Format("021504Z"))
+		}
+		cachedWX.AFD = afd
+		return nil
+	})
+}
+
+// updateAdvisories keeps the AIRMETs, SIGMETs and G-AIRMETs whose area
+// contains or comes within the configured radius of the airport and that
+// have not yet expired
+func updateAdvisories(ctx context.Context, src fetch.Source, area config.AdvisoryCfg, flags Flags) error {
+	cachedWX, err := readCachedWX(CachePath, flags)
+	if err != nil {
+		return err
+	}
+	if cachedWX.ICAO != *flags.Airport || (cachedWX.Lat == 0 && cachedWX.Lon == 0) {
+		return fmt.Errorf("no position cached for %s yet", *flags.Airport)
+	}
+
+	APIairsigmets, err := src.GetAirSigmets(ctx)
+	if err != nil {
+		return err
+	}
+	APIgairmets, err := src.GetGAirmets(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	advisories := []types.Advisory{}
+	keep := func(advisory types.Advisory, coords []types.AdvisoryPos) {
+		polygon := make([]geo.Point, 0, len(coords))
+		for _, c := range coords {
+			polygon = append(polygon, geo.Point{Lat: c.Lat, Lon: c.Lon})
+		}
+		distance := geo.DistanceToPolygonNM(cachedWX.Lat, cachedWX.Lon, polygon)
+		if distance > area.RadiusNM || advisory.ValidTo <= now {
+			return
+		}
+		advisory.Inside = distance == 0
+		advisory.DistanceNM = distance
+		advisories = append(advisories, advisory)
+	}
+
+	for i := range APIairsigmets {
+		if APIairsigmets[i].AirSigmetType == "OUTLOOK" {
+			continue
+		}
+		var advisory types.Advisory
+		if err := parse.BuildInternalAirSigmet(&APIairsigmets[i], &advisory); err != nil {
+			return err
+		}
+		keep(advisory, APIairsigmets[i].Coords)
+	}
+	for i := range APIgairmets {
+		if !parse.GAirmetHazard(APIgairmets[i].Hazard) {
+			continue
+		}
+		var advisory types.Advisory
+		if err := parse.BuildInternalGAirmet(&APIgairmets[i], &advisory); err != nil {
+			return err
+		}
+		keep(advisory, APIgairmets[i].Coords)
+	}
+	slog.Debug("Advisories", "received", len(APIairsigmets)+len(APIgairmets), "kept", len(advisories))
+
+	return modifyCache(flags, func(cachedWX *types.Airport) error {
+		if cachedWX.ICAO != *flags.Airport {
+			return fmt.Errorf("advisories for %s, cache holds %s", *flags.Airport, cachedWX.ICAO)
+		}
+		cachedWX.Advisories = types.AdvisoryArea{
+			RadiusNM:   area.RadiusNM,
+			Fetched:    now,
+			Advisories: advisories,
+		}
+		return nil
+	})
+}
+
+// withinAltitudes - reports without a usable altitude are kept, since a
+// pilot report near the field is rarely irrelevant
+func withinAltitudes(pirep types.PIREP, area config.PIREPCfg) bool {
+	if pirep.Altitude == nil || area.MaxAlt <= area.MinAlt {
+		return true
+	}
+	alt := int(*pirep.Altitude)
+	return alt >= area.MinAlt && alt <= area.MaxAlt
+}
+
+// crossCheckTAF decodes the raw text independently and logs any place it
+// disagrees with the API's decode
+func crossCheckTAF(taf types.TAF) {
+	raw, err := parse.ParseTAF(taf.Raw, time.Unix(taf.Issued, 0))
+	if err != nil {
+		slog.Debug("raw TAF decode failed", "error", err)
+		return
+	}
+	if diffs := parse.DiffTAF(taf, raw); len(diffs) > 0 {
+		slog.Debug("raw TAF decode differs from API", "diffs", strings.Join(diffs, "; "))
+	}
+}
+
+// cacheMu serializes read-modify-write cycles between product jobs
+var cacheMu sync.Mutex
+
+func modifyCache(flags Flags, apply func(cachedWX *types.Airport) error) error {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	cachedWX, err := readCachedWX(CachePath, flags)
+	if err != nil {
+		return err
+	}
+	if err := apply(&cachedWX); err != nil {
+		return err
+	}
 	return writeCachedWX(CachePath, cachedWX)
 }
 
@@ -121,32 +551,18 @@ func readCachedWX(jsonPath string, flags Flags) (types.Airport, error) {
 	if err != nil {
 		return types.Airport{}, err
 	}
-	jsonData, err := os.ReadFile(jsonPath)
-	if err != nil {
-		return types.Airport{}, err
-	}
-	var cachedWX types.Airport
-	err = json.Unmarshal(jsonData, &cachedWX)
-	return cachedWX, nil
+	return cache.Read(jsonPath)
 }
 
 func writeCachedWX(jsonPath string, cachedWX types.Airport) error {
-	jsonData, err := json.MarshalIndent(cachedWX, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(jsonPath, jsonData, 0644)
+	return cache.Write(jsonPath, cachedWX)
 }
 
 func getCachedICAO(cachePath string) (string, error) {
-	jsonData, err := os.ReadFile(cachePath)
+	cached, err := cache.Read(cachePath)
 	if err != nil {
 		return "", err
 	}
-	var cached types.Airport
-	if err := json.Unmarshal(jsonData, &cached); err != nil {
-		return "", err
-	}
 	return cached.ICAO, nil
 }
 
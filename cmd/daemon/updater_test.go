@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/house-holder/pilot-bar/internal/cache"
+	"github.com/house-holder/pilot-bar/internal/config"
+	"github.com/house-holder/pilot-bar/internal/fetch"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func testFlags(airport string, force bool) Flags {
+	empty, off := "", false
+	return Flags{
+		Airport:  &airport,
+		Debug:    &off,
+		Info:     &off,
+		Once:     &off,
+		Update:   &force,
+		Verbose:  &off,
+		Source:   &empty,
+		BaseURL:  &empty,
+		Fixtures: &empty,
+		Strict:   nil,
+		Depart:   &empty,
+	}
+}
+
+// useTempCache points the daemon at a cache file in a fresh directory
+func useTempCache(t *testing.T) string {
+	t.Helper()
+	saved := CachePath
+	CachePath = filepath.Join(t.TempDir(), "currentWX.json")
+	t.Cleanup(func() { CachePath = saved })
+	return CachePath
+}
+
+func TestUpdateFromFixtures(t *testing.T) {
+	path := useTempCache(t)
+	src, err := fetch.NewFixture("../../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Modules = config.ModuleCfg{METAR: true, TAF: true, AFD: true, AIRMET: true}
+
+	if err := Update(context.Background(), src, cfg, testFlags("KCGI", true)); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := cache.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ICAO != "KCGI" || got.Name == "" {
+		t.Errorf("airport = %q (%q)", got.ICAO, got.Name)
+	}
+	if got.Lat == 0 || got.Lon == 0 {
+		t.Errorf("position not cached: %v,%v", got.Lat, got.Lon)
+	}
+	// the newer of the two KCGI observations in metar_long.json
+	const raw = "METAR KCGI 291153Z 05009KT 5SM -RA BR FEW008 BKN014 OVC021 13/12 A2964 RMK AO2 SLP036 P0003 60063 70079 T01280122 10128 20122 56005"
+	if got.METAR.Raw != raw {
+		t.Errorf("METAR.Raw = %q", got.METAR.Raw)
+	}
+	if got.METAR.Reported.Epoch != 1761738780 {
+		t.Errorf("METAR.Reported.Epoch = %d", got.METAR.Reported.Epoch)
+	}
+	if got.METAR.Category() != "MVFR" {
+		t.Errorf("METAR category = %q", got.METAR.Category())
+	}
+	if got.METAR.Wind.Direction != 50 || got.METAR.Wind.Speed != 9 {
+		t.Errorf("wind = %+v", got.METAR.Wind)
+	}
+	if got.METAR.Visibility == nil || got.METAR.Visibility.Miles != 5 {
+		t.Errorf("visibility = %v", got.METAR.Visibility)
+	}
+	if got.Derived == nil || got.MagVar == nil {
+		t.Errorf("derived values or magnetic variation missing")
+	}
+	if len(got.TAF.Periods) == 0 {
+		t.Errorf("TAF not cached")
+	}
+	if len(got.AFD.Sections) == 0 {
+		t.Errorf("AFD not cached")
+	}
+	if got.LastUpdateEpoch == 0 {
+		t.Errorf("LastUpdateEpoch not set")
+	}
+
+	// a current cache is left alone unless forced
+	if err := Update(context.Background(), src, cfg, testFlags("KCGI", false)); err != nil {
+		t.Fatalf("second Update: %v", err)
+	}
+	again, err := cache.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.LastUpdateEpoch != got.LastUpdateEpoch {
+		t.Errorf("current cache was refreshed")
+	}
+}
+
+func TestUpdateUnknownStation(t *testing.T) {
+	useTempCache(t)
+	src, err := fetch.NewFixture("../../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(context.Background(), src, config.Default(), testFlags("KXXX", true)); err == nil {
+		t.Fatal("Update for a station with no METAR succeeded")
+	}
+}
+
+func TestUpdateFillsMissingPosition(t *testing.T) {
+	path := useTempCache(t)
+	// a cache for the right airport written without its position
+	stale := types.Airport{ICAO: "KCGI", Name: "CAPE GIRARDEAU RGNL, MO, US"}
+	if err := cache.Write(path, stale); err != nil {
+		t.Fatal(err)
+	}
+	src, err := fetch.NewFixture("../../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Update(context.Background(), src, config.Default(), testFlags("KCGI", true)); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := cache.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Lat == 0 || got.Lon == 0 || got.Elevation == 0 {
+		t.Errorf("position not filled in: %v,%v elev %d", got.Lat, got.Lon, got.Elevation)
+	}
+}
+
+func TestUpdateCategoryRules(t *testing.T) {
+	src, err := fetch.NewFixture("../../testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// KCGI 291153Z: 5SM, BKN014
+	for rules, want := range map[string]string{"": "MVFR", "faa": "MVFR", "icao": "IFR"} {
+		path := useTempCache(t)
+		cfg := config.Default()
+		cfg.Modules = config.ModuleCfg{METAR: true}
+		cfg.CategoryRules = rules
+		if err := Update(context.Background(), src, cfg, testFlags("KCGI", true)); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := cache.Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.METAR.FltCatDerived != want {
+			t.Errorf("categoryRules %q: FltCatDerived = %q, want %q", rules, got.METAR.FltCatDerived, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// genrunways trims the OurAirports runways.csv down to the open runways
+// internal/runways embeds, reading a saved copy (--in) or downloading it
+// (--url).
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// SourceURL is the OurAirports runways.csv, mirrored daily on GitHub
+const SourceURL = "https://davidmegginson.github.io/ourairports-data/runways.csv"
+
+// columns copied, in output order
+var columns = []string{
+	"airport_ident", "length_ft", "width_ft", "surface",
+	"le_ident", "le_heading_degT", "he_ident", "he_heading_degT",
+}
+
+func main() {
+	in := pflag.StringP("in", "i", "", "saved OurAirports runways.csv")
+	url := pflag.StringP("url", "u", "", "download runways.csv from here, e.g. "+SourceURL)
+	out := pflag.StringP("out", "o", "internal/runways/runways.csv", "output file")
+	prefixes := pflag.StringSlice("prefix", nil, "only airports whose ident starts with one of these, e.g. K,P")
+	pflag.Parse()
+
+	if (*in == "") == (*url == "") {
+		slog.Error("genrunways: give one of --in or --url")
+		os.Exit(2)
+	}
+	source, name, err := open(*in, *url)
+	if err != nil {
+		slog.Error("genrunways", "error", err)
+		os.Exit(1)
+	}
+	err = generate(source, name, *out, *prefixes)
+	source.Close()
+	if err != nil {
+		slog.Error("genrunways", "error", err)
+		os.Exit(1)
+	}
+}
+
+// open returns the source CSV and a name for it in errors
+func open(path, url string) (io.ReadCloser, string, error) {
+	if path != "" {
+		f, err := os.Open(path)
+		return f, path, err
+	}
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, url, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, url, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp.Body, url, nil
+}
+
+func generate(in io.Reader, inName, outPath string, prefixes []string) error {
+	r := csv.NewReader(in)
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("reading header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+	for _, name := range append(columns, "closed") {
+		if _, ok := index[name]; !ok {
+			return fmt.Errorf("%s: no %q column", inName, name)
+		}
+	}
+
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(outFile)
+	if err := w.Write(columns); err != nil {
+		outFile.Close()
+		return err
+	}
+
+	kept := 0
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			outFile.Close()
+			return fmt.Errorf("%s: %w", inName, err)
+		}
+		if !keep(record, index, prefixes) {
+			continue
+		}
+		row := make([]string, len(columns))
+		for i, name := range columns {
+			row[i] = record[index[name]]
+		}
+		if err := w.Write(row); err != nil {
+			outFile.Close()
+			return err
+		}
+		kept++
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		outFile.Close()
+		return err
+	}
+	slog.Info("genrunways", "runways", kept, "out", outPath)
+	return outFile.Close()
+}
+
+// keep drops closed runways, ones without both ends named, and airports
+// outside the requested prefixes
+func keep(record []string, index map[string]int, prefixes []string) bool {
+	if record[index["closed"]] == "1" {
+		return false
+	}
+	if record[index["le_ident"]] == "" || record[index["he_ident"]] == "" {
+		return false
+	}
+	if len(prefixes) == 0 {
+		return true
+	}
+	ident := record[index["airport_ident"]]
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(ident, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/mockawc"
+	"github.com/spf13/pflag"
+)
+
+type Flags struct {
+	Addr  *string
+	Dir   *string
+	Fails *[]string
+}
+
+func setupFlags() Flags {
+	addr := pflag.StringP("addr", "l", "127.0.0.1:8089", "listen address")
+	dir := pflag.StringP("dir", "d", "./testdata", "fixture dir")
+	fails := pflag.StringArrayP("fail", "f", nil,
+		"queue failures, e.g. metar=429:5,500,timeout:15s,empty,malformed (repeatable)")
+
+	pflag.Parse()
+	return Flags{
+		Addr:  addr,
+		Dir:   dir,
+		Fails: fails,
+	}
+}
+
+func main() {
+	flags := setupFlags()
+	handler := mockawc.NewHandler(*flags.Dir)
+
+	for _, spec := range *flags.Fails {
+		endpoint, list, ok := strings.Cut(spec, "=")
+		if !ok {
+			slog.Error("bad --fail, want endpoint=kind[,kind]", "spec", spec)
+			os.Exit(2)
+		}
+		for kind := range strings.SplitSeq(list, ",") {
+			failure, err := mockawc.ParseFailure(kind)
+			if err != nil {
+				slog.Error("bad --fail", "error", err)
+				os.Exit(2)
+			}
+			handler.Fail(endpoint, failure)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *flags.Addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("mock AWC listening", "base", "http://"+*flags.Addr+mockawc.APIPath)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("listen", "error", err)
+		os.Exit(1)
+	}
+}
@@ -1,7 +1,99 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/cache"
+	"github.com/house-holder/pilot-bar/internal/config"
+	"github.com/house-holder/pilot-bar/internal/format"
+	"github.com/spf13/pflag"
+)
+
+type Flags struct {
+	Cache      *string
+	StaleAfter *int
+	Stream     *bool
+	AFD        *bool
+}
+
+func setupFlags() Flags {
+	cachePath := pflag.StringP("cache", "c", cache.DefaultPath(), "path to daemon cache file")
+	staleAfter := pflag.IntP("stale", "s", 90, "minutes before an observation is stale")
+	stream := pflag.BoolP("stream", "w", false, "stay resident, print a JSON line on each change")
+	afd := pflag.Bool("afd", false, "print the cached Area Forecast Discussion and exit")
+
+	pflag.Parse()
+	return Flags{
+		Cache:      cachePath,
+		StaleAfter: staleAfter,
+		Stream:     stream,
+		AFD:        afd,
+	}
+}
+
+func loadTemplates() format.Templates {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("config", "error", err)
+		return format.Defaults()
+	}
+	templates, err := format.FromConfig(cfg.Format)
+	if err != nil {
+		slog.Error("config", "error", err)
+		return format.Defaults()
+	}
+	return templates
+}
+
+// printAFD writes the full discussion, e.g. for an on-click pager
+func printAFD(w io.Writer, cachePath string) error {
+	cached, err := cache.Read(cachePath)
+	if err != nil {
+		return err
+	}
+	if cached.AFD.Raw == "" {
+		return fmt.Errorf("no AFD cached for %s", cached.ICAO)
+	}
+	_, err = fmt.Fprintln(w, format.FullAFD(cached.AFD))
+	return err
+}
 
 func main() {
-	fmt.Println("Hello, World!")
+	flags := setupFlags()
+	slog.SetLogLoggerLevel(slog.LevelWarn)
+
+	if *flags.AFD {
+		if err := printAFD(os.Stdout, *flags.Cache); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	r := &renderer{
+		cachePath:  *flags.Cache,
+		staleAfter: time.Duration(*flags.StaleAfter) * time.Minute,
+		templates:  loadTemplates(),
+	}
+
+	if *flags.Stream {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := stream(ctx, os.Stdout, r); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := json.NewEncoder(os.Stdout).Encode(r.build(time.Now())); err != nil {
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/cache"
+	"github.com/house-holder/pilot-bar/internal/format"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+const (
+	ClassStale     = "stale"
+	ClassNoData    = "nodata"
+	ClassDeparture = "departure-warning"
+	ClassAFDNew    = "afd-new"
+	ClassAdvisory  = "advisory"
+	ClassSIGMET    = "sigmet"
+	ClassMinimums  = "below-minimums"
+	ClassHighDA    = "high-density-altitude"
+
+	ClassThunderstorm = "thunderstorm"
+	ClassFreezing     = "freezing-precip"
+	ClassSevereWx     = "severe-wx"
+)
+
+// Output is a single Waybar custom-module update (return-type: json)
+type Output struct {
+	Text       string   `json:"text"`
+	Alt        string   `json:"alt"`
+	Tooltip    string   `json:"tooltip"`
+	Class      []string `json:"class"`
+	Percentage int      `json:"percentage"`
+}
+
+type renderer struct {
+	cachePath  string
+	staleAfter time.Duration
+	templates  format.Templates
+}
+
+func (r *renderer) build(now time.Time) Output {
+	cached, err := cache.Read(r.cachePath)
+	if err != nil {
+		return noDataOutput(fmt.Sprintf("cache unavailable: %v", err))
+	}
+	if cached.METAR.Reported.Epoch == 0 {
+		return noDataOutput(fmt.Sprintf("no METAR cached for %s", cached.ICAO))
+	}
+	return r.airportOutput(cached, now)
+}
+
+func noDataOutput(reason string) Output {
+	return Output{
+		Text:    "--",
+		Alt:     ClassNoData,
+		Tooltip: reason,
+		Class:   []string{ClassNoData},
+	}
+}
+
+func (r *renderer) airportOutput(data types.Airport, now time.Time) Output {
+	age := now.Sub(time.Unix(data.METAR.Reported.Epoch, 0))
+	if age < 0 {
+		age = 0
+	}
+
+	category := "unk"
+	if fltCat := data.METAR.Category(); fltCat != "" {
+		category = strings.ToLower(fltCat)
+	}
+
+	fields := format.Data{Airport: data, Now: now}
+	out := Output{
+		Text:       r.templates.Text.Render(fields),
+		Alt:        category,
+		Tooltip:    r.templates.Tooltip.Render(fields),
+		Class:      []string{category},
+		Percentage: freshness(age, r.staleAfter),
+	}
+
+	if data.Departure != nil && len(data.Departure.Warnings) > 0 {
+		out.Class = append(out.Class, ClassDeparture)
+	}
+	if data.Minimums != nil && len(data.Minimums.Violations) > 0 {
+		out.Class = append(out.Class, ClassMinimums)
+	}
+	if data.Derived != nil && data.Derived.HighDA {
+		out.Class = append(out.Class, ClassHighDA)
+	}
+
+	out.Class = append(out.Class, weatherClasses(data.METAR.Weather)...)
+	out.Class = append(out.Class, advisoryClasses(data.Advisories, now)...)
+
+	if format.AFDIsNew(data.AFD, now) {
+		out.Class = append(out.Class, ClassAFDNew)
+	}
+
+	if age > r.staleAfter {
+		out.Alt = ClassStale
+		out.Class = append([]string{ClassStale}, out.Class...)
+	}
+	return out
+}
+
+// weatherClasses flags present weather worth a distinct style
+func weatherClasses(weather []types.Weather) []string {
+	var ts, fz, severe bool
+	for _, wx := range weather {
+		ts = ts || (wx.Thunderstorm && !wx.Vicinity)
+		fz = fz || wx.FreezingPrecip
+		severe = severe || wx.Severe
+	}
+
+	var classes []string
+	if ts {
+		classes = append(classes, ClassThunderstorm)
+	}
+	if fz {
+		classes = append(classes, ClassFreezing)
+	}
+	if severe {
+		classes = append(classes, ClassSevereWx)
+	}
+	return classes
+}
+
+// advisoryClasses flags any active advisory, and SIGMETs over the field
+func advisoryClasses(area types.AdvisoryArea, now time.Time) []string {
+	active := format.ActiveAdvisories(area, now)
+	if len(active) == 0 {
+		return nil
+	}
+	classes := []string{ClassAdvisory}
+	for _, advisory := range active {
+		if advisory.Inside && format.IsSIGMET(advisory) {
+			return append(classes, ClassSIGMET)
+		}
+	}
+	return classes
+}
+
+// freshness maps observation age onto 100 (new) .. 0 (stale)
+func freshness(age, staleAfter time.Duration) int {
+	if staleAfter <= 0 || age >= staleAfter {
+		return 0
+	}
+	return int(100 - (age * 100 / staleAfter))
+}
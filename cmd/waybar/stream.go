@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// tickInterval bounds how late the age label (and stale class) can be
+// relative to the minute it changes on.
+const tickInterval = 15 * time.Second
+
+// stream writes one JSON line on startup, then another whenever the cache
+// changes or the rendered output would differ (age rollover, going stale).
+func stream(ctx context.Context, w io.Writer, r *renderer) error {
+	changes := watchCache(ctx, r.cachePath)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	var last []byte
+
+	emit := func() error {
+		line, err := json.Marshal(r.build(time.Now()))
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(line, last) {
+			return nil
+		}
+		last = line
+		_, err = w.Write(append(line, '\n'))
+		return err
+	}
+
+	if err := emit(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			if err := emit(); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := emit(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/cache"
+	"github.com/house-holder/pilot-bar/internal/format"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func TestStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "currentWX.json")
+	if err := cache.Write(path, types.Airport{ICAO: "KSGF"}); err != nil {
+		t.Fatal(err)
+	}
+	r := &renderer{cachePath: path, staleAfter: 90 * time.Minute, templates: format.Defaults()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- stream(ctx, pw, r)
+		pw.Close()
+	}()
+
+	lines := make(chan Output)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			var out Output
+			if err := json.Unmarshal(scanner.Bytes(), &out); err != nil {
+				t.Errorf("line %q: %v", scanner.Text(), err)
+				return
+			}
+			lines <- out
+		}
+	}()
+	next := func() Output {
+		t.Helper()
+		select {
+		case out, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed early")
+			}
+			return out
+		case <-time.After(5 * time.Second):
+			t.Fatal("no line written")
+		}
+		return Output{}
+	}
+
+	if out := next(); out.Tooltip != "no METAR cached for KSGF" {
+		t.Errorf("first line = %+v", out)
+	}
+
+	// each cache replacement is one more line
+	for _, icao := range []string{"KJLN", "KBBG"} {
+		if err := cache.Write(path, types.Airport{ICAO: icao}); err != nil {
+			t.Fatal(err)
+		}
+		if out := next(); out.Tooltip != "no METAR cached for "+icao {
+			t.Errorf("after writing %s: %+v", icao, out)
+		}
+	}
+
+	// a rewrite that renders the same is not repeated
+	if err := cache.Write(path, types.Airport{ICAO: "KBBG"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case out := <-lines:
+		t.Errorf("unchanged cache wrote %+v", out)
+	case <-time.After(time.Second):
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("stream = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream didn't exit on cancel")
+	}
+	if _, ok := <-lines; ok {
+		t.Error("line written after cancel")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+const pollInterval = 2 * time.Second
+
+// watchCache signals on the returned channel whenever the cache file is
+// replaced or rewritten. Platform watchers are preferred; polling is the
+// fallback when they are unavailable.
+func watchCache(ctx context.Context, path string) <-chan struct{} {
+	changes, err := watchNative(ctx, path)
+	if err == nil {
+		return changes
+	}
+	slog.Warn("native file watch unavailable, polling", "error", err)
+	return watchPoll(ctx, path)
+}
+
+func watchPoll(ctx context.Context, path string) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		var lastMod time.Time
+		if info, err := os.Stat(path); err == nil {
+			lastMod = info.ModTime()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil || info.ModTime().Equal(lastMod) {
+					continue
+				}
+				lastMod = info.ModTime()
+				notify(changes)
+			}
+		}
+	}()
+
+	return changes
+}
+
+// notify performs a non-blocking send; one pending signal is enough since
+// every emit re-reads the whole cache.
+func notify(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,78 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// watchNative uses inotify on the cache's parent dir, since the daemon
+// replaces the file by rename and a watch on the old inode would go quiet.
+func watchNative(ctx context.Context, path string) (<-chan struct{}, error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+
+	file := os.NewFile(uintptr(fd), "inotify")
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		<-ctx.Done()
+		file.Close()
+	}()
+
+	go func() {
+		defer close(changes)
+		buf := make([]byte, 4096)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			if eventsMatch(buf[:n], name) {
+				notify(changes)
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+func eventsMatch(buf []byte, name string) bool {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		start := offset + syscall.SizeofInotifyEvent
+		end := start + int(event.Len)
+		if end > len(buf) {
+			return false
+		}
+
+		eventName := string(buf[start:end])
+		for i := 0; i < len(eventName); i++ {
+			if eventName[i] == 0 {
+				eventName = eventName[:i]
+				break
+			}
+		}
+		if eventName == name {
+			return true
+		}
+		offset = end
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+func watchNative(_ context.Context, _ string) (<-chan struct{}, error) {
+	return nil, errors.New("no native watcher on this platform")
+}
@@ -3,3 +3,65 @@
 // per-airport in a simple dir named for the field ID (KORD/, KEWR/, KDEN/)
 
 package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// DefaultPath is currentWX.json in the user's cache directory
+// (~/.cache/pilot-bar on Linux), or the working directory without one
+func DefaultPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "currentWX.json"
+	}
+	return filepath.Join(dir, "pilot-bar", "currentWX.json")
+}
+
+// Read loads the active cache file
+func Read(path string) (types.Airport, error) {
+	jsonData, err := os.ReadFile(path)
+	if err != nil {
+		return types.Airport{}, err
+	}
+	var cached types.Airport
+	if err := json.Unmarshal(jsonData, &cached); err != nil {
+		return types.Airport{}, err
+	}
+	return cached, nil
+}
+
+// Write replaces the active cache file. The data is written to a temp file
+// and renamed into place so readers never see a partial file.
+func Write(path string, data types.Airport) error {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".currentWX-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(jsonData); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,136 @@
+// 'category' derives the flight category (VFR/MVFR/IFR/LIFR) from ceiling
+// and visibility, for reports and forecasts the API doesn't classify.
+package category
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// Limit puts conditions into Category when the ceiling is below Ceiling
+// or visibility below Visibility (statute miles)
+type Limit struct {
+	Category   string
+	Ceiling    types.Feet
+	Visibility float64
+	Inclusive  bool // at the limit counts too
+}
+
+// Ruleset lists its limits worst first; anything better is VFR
+type Ruleset struct {
+	Name   string
+	Limits []Limit
+}
+
+// FAA is the US scheme of AIM 7-1-7 (Categorical Outlooks): LIFR below
+// 500 ft or 1 SM, IFR below 1000 ft or 3 SM, MVFR at or below 3000 ft or 5 SM
+var FAA = Ruleset{
+	Name: "faa",
+	Limits: []Limit{
+		{Category: "LIFR", Ceiling: 500, Visibility: 1},
+		{Category: "IFR", Ceiling: 1000, Visibility: 3},
+		{Category: "MVFR", Ceiling: 3000, Visibility: 5, Inclusive: true},
+	},
+}
+
+// ICAO puts the same categories on the ICAO/European minima: LIFR below
+// the Special VFR minimum of 600 ft or 1500 m (SERA.5010), IFR below the
+// 1500 ft or 5 km needed for VFR in a control zone (Annex 2, 4.6), and MVFR
+// short of CAVOK's 5000 ft and 10 km (Annex 3, Appendix 3, 2.2). CAVOK
+// allows no cloud at all below 5000 ft; only the ceiling is checked here.
+var ICAO = Ruleset{
+	Name: "icao",
+	Limits: []Limit{
+		{Category: "LIFR", Ceiling: 600, Visibility: 1500 / metersPerMile},
+		{Category: "IFR", Ceiling: 1500, Visibility: 5000 / metersPerMile},
+		{Category: "MVFR", Ceiling: 5000, Visibility: 10000 / metersPerMile},
+	},
+}
+
+const metersPerMile = 1609.344
+
+// Lookup finds a ruleset by name; empty means FAA
+func Lookup(name string) (Ruleset, error) {
+	switch strings.ToLower(name) {
+	case "", "faa":
+		return FAA, nil
+	case "icao":
+		return ICAO, nil
+	default:
+		return Ruleset{}, fmt.Errorf("unknown category ruleset %q", name)
+	}
+}
+
+// Classify puts a ceiling and visibility into a category. nil elements
+// aren't known and don't lower it; a "more than" visibility (P6SM, CAVOK)
+// is never limiting.
+func (r Ruleset) Classify(ceiling *types.Feet, visibility *types.Visibility) string {
+	for _, limit := range r.Limits {
+		if limit.below(ceiling, visibility) {
+			return limit.Category
+		}
+	}
+	return "VFR"
+}
+
+func (l Limit) below(ceiling *types.Feet, visibility *types.Visibility) bool {
+	if ceiling != nil && (*ceiling < l.Ceiling || l.Inclusive && *ceiling == l.Ceiling) {
+		return true
+	}
+	if visibility == nil || visibility.Plus {
+		return false
+	}
+	miles := float64(visibility.Miles)
+	return miles < l.Visibility || l.Inclusive && miles == l.Visibility
+}
+
+// METAR classifies an observation; "" when it reported neither sky nor
+// visibility (an empty Clouds is sky clear, nil not reported)
+func (r Ruleset) METAR(m types.METAR) string {
+	if m.Visibility == nil && m.Clouds == nil && m.VertVis == nil {
+		return ""
+	}
+	return r.Classify(Ceiling(m.Clouds, m.VertVis), m.Visibility)
+}
+
+// Ceiling is the lowest broken/overcast layer or vertical visibility
+func Ceiling(clouds []types.CloudData, vertVis *types.Feet) *types.Feet {
+	var ceiling *types.Feet
+	if vertVis != nil {
+		base := *vertVis
+		ceiling = &base
+	}
+	for _, layer := range clouds {
+		if layer.Coverage != "broken" && layer.Coverage != "overcast" {
+			continue
+		}
+		if ceiling == nil || layer.Base < *ceiling {
+			base := layer.Base
+			ceiling = &base
+		}
+	}
+	return ceiling
+}
+
+// rank orders categories worst first; unknown sorts after VFR
+func rank(fltCat string) int {
+	switch fltCat {
+	case "LIFR":
+		return 0
+	case "IFR":
+		return 1
+	case "MVFR":
+		return 2
+	case "VFR":
+		return 3
+	default:
+		return 4
+	}
+}
+
+// WorseThan reports whether a is a lower category than b
+func WorseThan(a, b string) bool {
+	return rank(a) < rank(b)
+}
@@ -0,0 +1,143 @@
+package category
+
+import (
+	"testing"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func feet(f types.Feet) *types.Feet { return &f }
+
+func miles(m float64) *types.Visibility {
+	v := types.VisMiles(m)
+	return &v
+}
+
+func meters(m int) *types.Visibility {
+	v := types.VisMeters(m)
+	return &v
+}
+
+func TestCeiling(t *testing.T) {
+	layer := func(coverage string, base types.Feet) types.CloudData {
+		return types.CloudData{Coverage: coverage, Base: base}
+	}
+	tests := []struct {
+		name    string
+		clouds  []types.CloudData
+		vertVis *types.Feet
+		want    *types.Feet
+	}{
+		{"few and scattered aren't a ceiling", []types.CloudData{layer("few", 800), layer("scattered", 1200)}, nil, nil},
+		{"lowest broken", []types.CloudData{layer("few", 800), layer("broken", 2500), layer("overcast", 4000)}, nil, feet(2500)},
+		{"out of order", []types.CloudData{layer("overcast", 4000), layer("broken", 1500)}, nil, feet(1500)},
+		{"vertical visibility", nil, feet(200), feet(200)},
+		{"vertical visibility below a layer", []types.CloudData{layer("broken", 300)}, feet(100), feet(100)},
+		{"layer below vertical visibility", []types.CloudData{layer("broken", 100)}, feet(300), feet(100)},
+		{"sky clear", []types.CloudData{}, nil, nil},
+	}
+	for _, tt := range tests {
+		got := Ceiling(tt.clouds, tt.vertVis)
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("%s: Ceiling = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyFAA(t *testing.T) {
+	tests := []struct {
+		ceiling    *types.Feet
+		visibility *types.Visibility
+		want       string
+	}{
+		{feet(499), nil, "LIFR"},
+		{feet(500), nil, "IFR"},
+		{feet(999), nil, "IFR"},
+		{feet(1000), nil, "MVFR"},
+		{feet(3000), nil, "MVFR"},
+		{feet(3100), nil, "VFR"},
+		{nil, miles(0.75), "LIFR"},
+		{nil, miles(1), "IFR"},
+		{nil, miles(2.5), "IFR"},
+		{nil, miles(3), "MVFR"},
+		{nil, miles(5), "MVFR"},
+		{nil, miles(6), "VFR"},
+		{nil, &types.Visibility{Miles: 6, Plus: true}, "VFR"},
+		{nil, &types.Visibility{Miles: 0.25, Minus: true}, "LIFR"},
+		{feet(4000), miles(2), "IFR"}, // the worse of the two
+		{feet(400), miles(10), "LIFR"},
+		{nil, nil, "VFR"},
+	}
+	for _, tt := range tests {
+		if got := FAA.Classify(tt.ceiling, tt.visibility); got != tt.want {
+			t.Errorf("FAA.Classify(%v, %v) = %s, want %s", tt.ceiling, tt.visibility, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyICAO(t *testing.T) {
+	tests := []struct {
+		ceiling    *types.Feet
+		visibility *types.Visibility
+		want       string
+	}{
+		{feet(500), nil, "LIFR"},
+		{feet(600), nil, "IFR"},
+		{feet(1400), nil, "IFR"},
+		{feet(1500), nil, "MVFR"},
+		{feet(4900), nil, "MVFR"},
+		{feet(5000), nil, "VFR"},
+		{nil, meters(1400), "LIFR"},
+		{nil, meters(1500), "IFR"},
+		{nil, meters(4900), "IFR"},
+		{nil, meters(5000), "MVFR"},
+		{nil, meters(8000), "MVFR"},
+		{nil, meters(9999), "VFR"},
+		{nil, miles(6), "MVFR"}, // 6 SM is under 10 km
+	}
+	for _, tt := range tests {
+		if got := ICAO.Classify(tt.ceiling, tt.visibility); got != tt.want {
+			t.Errorf("ICAO.Classify(%v, %v) = %s, want %s", tt.ceiling, tt.visibility, got, tt.want)
+		}
+	}
+}
+
+func TestMETAR(t *testing.T) {
+	tests := []struct {
+		name  string
+		metar types.METAR
+		want  string
+	}{
+		{"nothing reported", types.METAR{}, ""},
+		{"sky clear, no visibility", types.METAR{Clouds: []types.CloudData{}}, "VFR"},
+		{"visibility, no sky", types.METAR{Visibility: miles(2)}, "IFR"},
+		{"vertical visibility", types.METAR{VertVis: feet(200), Visibility: miles(0.5)}, "LIFR"},
+		{"overcast", types.METAR{Clouds: []types.CloudData{{Coverage: "overcast", Base: 800}}, Visibility: miles(10)}, "IFR"},
+	}
+	for _, tt := range tests {
+		if got := FAA.METAR(tt.metar); got != tt.want {
+			t.Errorf("%s: FAA.METAR = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for name, want := range map[string]string{"": "faa", "FAA": "faa", "icao": "icao", "ICAO": "icao"} {
+		rules, err := Lookup(name)
+		if err != nil || rules.Name != want {
+			t.Errorf("Lookup(%q) = %s, %v; want %s", name, rules.Name, err, want)
+		}
+	}
+	if _, err := Lookup("eu"); err == nil {
+		t.Error("Lookup accepted an unknown ruleset")
+	}
+}
+
+func TestWorseThan(t *testing.T) {
+	if !WorseThan("LIFR", "IFR") || !WorseThan("MVFR", "VFR") || WorseThan("VFR", "MVFR") || WorseThan("IFR", "IFR") {
+		t.Error("categories out of order")
+	}
+	if !WorseThan("VFR", "") {
+		t.Error("an unknown category should sort after VFR")
+	}
+}
@@ -1,15 +1,34 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 )
 
 type Config struct {
-	Airport string    `json:"airport"`
-	Modules ModuleCfg `json:"modules"`
+	Airport  string      `json:"airport"`
+	Modules  ModuleCfg   `json:"modules"`
+	Format   FormatCfg   `json:"format"`
+	Source   SourceCfg   `json:"source"`
+	PIREP    PIREPCfg    `json:"pirepArea"`
+	Advisory AdvisoryCfg `json:"advisoryArea"`
+	Minimums MinimumsCfg `json:"minimums"`
+
+	// HighDensityAlt flags density altitude this many feet above the field
+	HighDensityAlt int `json:"highDensityAlt"`
+
+	// CategoryRules names the thresholds flight categories are derived by
+	// when the API doesn't give one: "faa" (the default) or "icao"
+	CategoryRules string `json:"categoryRules"`
+
+	// MagneticModel is a NOAA World Magnetic Model coefficient file (WMM.COF)
+	// to use instead of the built-in one, e.g. once a newer model is out
+	MagneticModel string `json:"magneticModel"`
 }
 
 type ModuleCfg struct {
@@ -20,6 +39,54 @@ type ModuleCfg struct {
 	PIREP  bool `json:"pirep"`
 }
 
+// SourceCfg selects where weather comes from: "awc" (aviationweather.gov,
+// or another server at BaseURL) or "fixture" (JSON files in FixtureDir).
+// NWSBaseURL overrides api.weather.gov, used for the AFD. Strict fails a
+// fetch whose records have unknown or malformed fields instead of skipping
+// the bad records and counting them in a warning.
+type SourceCfg struct {
+	Kind       string `json:"kind"`
+	BaseURL    string `json:"baseURL"`
+	NWSBaseURL string `json:"nwsBaseURL"`
+	FixtureDir string `json:"fixtureDir"`
+	Strict     bool   `json:"strict"`
+}
+
+// PIREPCfg bounds which pilot reports are kept: within RadiusNM of the
+// airport, reported between MinAlt and MaxAlt (feet MSL), and no older
+// than AgeHours
+type PIREPCfg struct {
+	RadiusNM float64 `json:"radiusNm"`
+	MinAlt   int     `json:"minAlt"`
+	MaxAlt   int     `json:"maxAlt"`
+	AgeHours int     `json:"ageHours"`
+}
+
+// AdvisoryCfg - AIRMETs and SIGMETs are kept when the airport is inside
+// the area or within RadiusNM of its edge
+type AdvisoryCfg struct {
+	RadiusNM float64 `json:"radiusNm"`
+}
+
+// MinimumsCfg is the pilot's personal minimums; zero values aren't checked.
+// TAFHours also checks the forecast that many hours ahead.
+type MinimumsCfg struct {
+	Ceiling          int     `json:"ceiling"`    // feet AGL
+	Visibility       float64 `json:"visibility"` // statute miles
+	MaxWind          int     `json:"maxWind"`    // knots, gusts included
+	MaxGustSpread    int     `json:"maxGustSpread"`
+	MaxCrosswind     int     `json:"maxCrosswind"`
+	NoFreezingPrecip bool    `json:"noFreezingPrecip"`
+	DayVFROnly       bool    `json:"dayVfrOnly"`
+	TAFHours         int     `json:"tafHours"`
+}
+
+// FormatCfg holds the bar templates; empty strings fall back to defaults
+type FormatCfg struct {
+	Text    string `json:"text"`
+	Tooltip string `json:"tooltip"`
+}
+
 func getConfigFile() (string, error) {
 	configDir := os.Getenv("XDG_CONFIG_HOME")
 
@@ -36,29 +103,50 @@ func getConfigFile() (string, error) {
 	return configFile, nil
 }
 
-// Load - satisfied LSP noise
+// Default is the config used when no file exists, and the base that a
+// config file's values are layered onto
+func Default() *Config {
+	return &Config{
+		Modules: ModuleCfg{
+			METAR: true,
+		},
+		Source: SourceCfg{
+			Kind:       "awc",
+			FixtureDir: "./testdata",
+		},
+		PIREP: PIREPCfg{
+			RadiusNM: 50,
+			MaxAlt:   18000,
+			AgeHours: 2,
+		},
+		Advisory: AdvisoryCfg{
+			RadiusNM: 25,
+		},
+		HighDensityAlt: 2000,
+	}
+}
+
+// Load reads the user's config file. A missing file is not an error.
 func Load() (*Config, error) {
 	configFile, err := getConfigFile()
 	if err != nil {
 		return nil, fmt.Errorf("error getting config file: %w", err)
 	}
-	slog.Info("found config file", "file", configFile)
-
-	// TODO: find a way to navigate things like:
-	// 		- config file doesn't exist
-	//		- config file is unreadable
-	//		- config file is broken/invalid JSON
 
-	cfg, err := os.ReadFile(configFile)
+	cfg := Default()
+	data, err := os.ReadFile(configFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Info("no config file, using defaults", "file", configFile)
+		return cfg, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", configFile, err)
+	}
 	slog.Info("read config file", "file", configFile)
 
-	return &Config{
-		Airport: string(cfg),
-		Modules: ModuleCfg{
-			METAR: true,
-		},
-	}, nil
+	return cfg, nil
 }
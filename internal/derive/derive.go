@@ -0,0 +1,102 @@
+// 'derive' works out performance numbers and simple estimates from an
+// observation: pressure and density altitude, humidity, convective cloud
+// base and freezing level.
+package derive
+
+import (
+	"math"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+const (
+	stdPressureInHg = 29.92126
+	ftPerMeter      = 3.28084
+	hPaPerInHg      = 33.8639
+)
+
+// Compute fills in everything derivable from the METAR at a field of the
+// given elevation; nil when there's no altimeter setting to work from.
+// Without a temperature only pressure altitude is given. Without a
+// dewpoint, humidity and cloud base are left out and density altitude is
+// worked for dry air.
+func Compute(elevation types.Feet, m types.METAR) *types.Derived {
+	if m.Altimeter == 0 {
+		return nil
+	}
+	derived := &types.Derived{PressureAlt: PressureAltitude(elevation, m.Altimeter)}
+	if m.Temp.NoTemp {
+		return derived
+	}
+	temp, dew := m.Temp.AmbientExact, m.Temp.DewpointExact
+	freezing := FreezingLevel(elevation, temp)
+	derived.FreezingLevel = &freezing
+	if m.Temp.NoDewpoint {
+		da := densityAltitude(elevation, m.Altimeter, temp, 0)
+		derived.DensityAlt = &da
+		return derived
+	}
+	da := DensityAltitude(elevation, m.Altimeter, temp, dew)
+	rh, base := RelativeHumidity(temp, dew), CloudBase(temp, dew)
+	derived.DensityAlt = &da
+	derived.RelHumidity = &rh
+	derived.CloudBase = &base
+	return derived
+}
+
+// PressureAltitude is the field's height in the standard atmosphere for the
+// altimeter setting
+func PressureAltitude(elevation types.Feet, altimeter types.InHg) types.Feet {
+	pa := float64(elevation) + 145366.45*(1-math.Pow(float64(altimeter)/stdPressureInHg, 0.190284))
+	return types.Feet(math.Round(pa))
+}
+
+// DensityAltitude is the standard-atmosphere height with the same air
+// density, allowing for humidity through the virtual temperature
+func DensityAltitude(elevation types.Feet, altimeter types.InHg, tempC, dewpointC float64) types.Feet {
+	return densityAltitude(elevation, altimeter, tempC, vaporPressure(dewpointC))
+}
+
+// densityAltitude takes the vapour pressure in hPa, zero for dry air
+func densityAltitude(elevation types.Feet, altimeter types.InHg, tempC, vaporHPa float64) types.Feet {
+	stationInHg := StationPressure(elevation, altimeter)
+	vaporInHg := vaporHPa / hPaPerInHg
+	virtualK := (tempC + 273.15) / (1 - vaporInHg/stationInHg*(1-0.622))
+	rankine := virtualK * 9 / 5
+	da := 145442.16 * (1 - math.Pow(17.326*stationInHg/rankine, 0.235))
+	return types.Feet(math.Round(da))
+}
+
+// StationPressure is the pressure at the field, inHg, from the altimeter
+// setting
+func StationPressure(elevation types.Feet, altimeter types.InHg) float64 {
+	meters := float64(elevation) / ftPerMeter
+	return float64(altimeter) * math.Pow((288-0.0065*meters)/288, 5.2561)
+}
+
+// RelativeHumidity in percent, from temperature and dewpoint
+func RelativeHumidity(tempC, dewpointC float64) float64 {
+	return math.Min(100, 100*vaporPressure(dewpointC)/vaporPressure(tempC))
+}
+
+// vaporPressure is the saturation vapour pressure in hPa (Magnus formula)
+func vaporPressure(tempC float64) float64 {
+	return 6.1094 * math.Exp(17.625*tempC/(tempC+243.04))
+}
+
+// CloudBase estimates the base of convective cloud above the field: a
+// rising parcel closes the temperature/dewpoint spread about 2.5°C per
+// 1000 ft
+func CloudBase(tempC, dewpointC float64) types.Feet {
+	spread := math.Max(0, tempC-dewpointC)
+	return types.Feet(math.Round(spread / 2.5 * 1000))
+}
+
+// FreezingLevel estimates the 0°C height MSL from the surface temperature
+// and the standard 2°C per 1000 ft lapse rate
+func FreezingLevel(elevation types.Feet, tempC float64) types.Feet {
+	if tempC <= 0 {
+		return elevation
+	}
+	return elevation + types.Feet(math.Round(tempC/2*1000))
+}
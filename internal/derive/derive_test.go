@@ -0,0 +1,150 @@
+package derive
+
+import (
+	"math"
+	"testing"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// dry stands in for a dewpoint with no measurable vapour
+const dry = -100
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestPressureAltitude(t *testing.T) {
+	tests := []struct {
+		elevation types.Feet
+		altimeter types.InHg
+		want      float64
+	}{
+		// the ICAO standard atmosphere: 29.92 inHg at sea level, 24.896 at
+		// 5000 ft, 20.577 at 10000 ft
+		{0, 29.92, 0},
+		{0, 24.896, 5000},
+		{0, 20.577, 10000},
+		{5000, 29.92, 5000},
+		// about 1000 ft per inch of mercury near sea level
+		{1000, 30.42, 540},
+		{1000, 29.42, 1470},
+	}
+	for _, tt := range tests {
+		if got := PressureAltitude(tt.elevation, tt.altimeter); !near(float64(got), tt.want, 15) {
+			t.Errorf("PressureAltitude(%v, %v) = %v, want %v", tt.elevation, tt.altimeter, got, tt.want)
+		}
+	}
+}
+
+func TestDensityAltitude(t *testing.T) {
+	tests := []struct {
+		name      string
+		elevation types.Feet
+		temp      float64
+		want      float64
+		tolerance float64
+	}{
+		// standard temperature gives density altitude equal to pressure
+		// altitude (15°C falling 1.98°C per 1000 ft)
+		{"ISA sea level", 0, 15, 0, 50},
+		{"ISA 5000 ft", 5000, 5.1, 5000, 50},
+		{"ISA 10000 ft", 10000, -4.8, 10000, 50},
+		// the E6B rule of about 120 ft per °C off standard
+		{"hot", 5000, 30, 5000 + 120*24.9, 250},
+		{"cold, sub-zero", 0, -20, -120 * 35, 400},
+	}
+	for _, tt := range tests {
+		got := DensityAltitude(tt.elevation, 29.92, tt.temp, dry)
+		if !near(float64(got), tt.want, tt.tolerance) {
+			t.Errorf("%s: DensityAltitude = %v, want %.0f±%.0f", tt.name, got, tt.want, tt.tolerance)
+		}
+	}
+
+	// moist air is lighter: a 25°C dewpoint on a 35°C day adds a few
+	// hundred feet over dry air
+	dryDA := DensityAltitude(0, 29.92, 35, dry)
+	humidDA := DensityAltitude(0, 29.92, 35, 25)
+	if diff := humidDA - dryDA; diff < 250 || diff > 600 {
+		t.Errorf("humidity added %v ft (dry %v, humid %v)", diff, dryDA, humidDA)
+	}
+}
+
+func TestRelativeHumidity(t *testing.T) {
+	tests := []struct{ temp, dewpoint, want float64 }{
+		{20, 10, 52.5},
+		{30, 20, 55.1},
+		{15, 15, 100},
+		{0, -10, 46.9},
+		{-10, -15, 66.8},
+		{10, 12, 100}, // a dewpoint above the temperature is saturated
+	}
+	for _, tt := range tests {
+		if got := RelativeHumidity(tt.temp, tt.dewpoint); !near(got, tt.want, 0.5) {
+			t.Errorf("RelativeHumidity(%v, %v) = %.1f, want %.1f", tt.temp, tt.dewpoint, got, tt.want)
+		}
+	}
+}
+
+func TestCloudBase(t *testing.T) {
+	tests := []struct {
+		temp, dewpoint float64
+		want           types.Feet
+	}{
+		{20, 10, 4000},
+		{15, 12.5, 1000},
+		{-5, -10, 2000},
+		{8, 8, 0},
+		{8, 9, 0},
+	}
+	for _, tt := range tests {
+		if got := CloudBase(tt.temp, tt.dewpoint); got != tt.want {
+			t.Errorf("CloudBase(%v, %v) = %v, want %v", tt.temp, tt.dewpoint, got, tt.want)
+		}
+	}
+}
+
+func TestFreezingLevel(t *testing.T) {
+	tests := []struct {
+		elevation types.Feet
+		temp      float64
+		want      types.Feet
+	}{
+		{1000, 10, 6000},
+		{0, 15, 7500},
+		{5000, 1, 5500},
+		{1000, 0, 1000},
+		{1000, -8, 1000},
+	}
+	for _, tt := range tests {
+		if got := FreezingLevel(tt.elevation, tt.temp); got != tt.want {
+			t.Errorf("FreezingLevel(%v, %v) = %v, want %v", tt.elevation, tt.temp, got, tt.want)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	m := types.METAR{
+		Altimeter: 29.92,
+		Temp:      types.TempData{AmbientExact: 20, DewpointExact: 10},
+	}
+	d := Compute(1000, m)
+	if d == nil || d.DensityAlt == nil || d.RelHumidity == nil || d.CloudBase == nil || d.FreezingLevel == nil {
+		t.Fatalf("Compute = %+v", d)
+	}
+	if *d.CloudBase != 4000 || *d.FreezingLevel != 11000 || !near(*d.RelHumidity, 52.5, 0.5) {
+		t.Errorf("Compute = %+v", d)
+	}
+
+	m.Temp.NoDewpoint = true
+	if d := Compute(1000, m); d.DensityAlt == nil || d.RelHumidity != nil || d.CloudBase != nil {
+		t.Errorf("without a dewpoint: %+v", d)
+	}
+	m.Temp.NoTemp = true
+	if d := Compute(1000, m); d.PressureAlt == 0 || d.DensityAlt != nil || d.FreezingLevel != nil {
+		t.Errorf("without a temperature: %+v", d)
+	}
+	if d := Compute(1000, types.METAR{}); d != nil {
+		t.Errorf("without an altimeter: %+v", d)
+	}
+}
@@ -0,0 +1,49 @@
+package fetch
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// GetAirSigmets loads the current domestic SIGMETs, convective SIGMETs and
+// AIRMETs. The endpoint has no area filter; the caller checks geometry.
+func (c *Client) GetAirSigmets(ctx context.Context) ([]types.AirSigmetResponse, error) {
+	query := url.Values{"format": {"json"}}
+
+	var payload []types.AirSigmetResponse
+	if err := c.getJSON(ctx, "AIRSIGMET", "/airsigmet", query, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// GetGAirmets loads the current G-AIRMET snapshots
+func (c *Client) GetGAirmets(ctx context.Context) ([]types.GAirmetResponse, error) {
+	query := url.Values{"format": {"json"}}
+
+	var payload []types.GAirmetResponse
+	if err := c.getJSON(ctx, "G-AIRMET", "/gairmet", query, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// GetAirSigmets returns every fixture record; times are as recorded
+func (f *Fixture) GetAirSigmets(ctx context.Context) ([]types.AirSigmetResponse, error) {
+	var records []types.AirSigmetResponse
+	if err := f.load(ctx, "airsigmet*.json", &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetGAirmets returns every fixture record; times are as recorded
+func (f *Fixture) GetGAirmets(ctx context.Context) ([]types.GAirmetResponse, error) {
+	var records []types.GAirmetResponse
+	if err := f.load(ctx, "gairmet*.json", &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
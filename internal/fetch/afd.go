@@ -0,0 +1,81 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+type nwsPoint struct {
+	Properties struct {
+		CWA string `json:"cwa"`
+	} `json:"properties"`
+}
+
+type nwsProductList struct {
+	Graph []struct {
+		ID           string `json:"id"`
+		IssuanceTime string `json:"issuanceTime"`
+	} `json:"@graph"`
+}
+
+// GetAFD loads the latest Area Forecast Discussion from the NWS office
+// whose warning area contains the point
+func (c *Client) GetAFD(ctx context.Context, lat, lon float64) (types.AFDresponse, error) {
+	var point nwsPoint
+	pointURL := fmt.Sprintf("%s/points/%.4f,%.4f", c.NWSBaseURL, lat, lon)
+	if err := c.getURL(ctx, "AFD office", pointURL, &point); err != nil {
+		return types.AFDresponse{}, err
+	}
+	office := point.Properties.CWA
+	if office == "" {
+		return types.AFDresponse{}, fmt.Errorf("no NWS office covers %.4f,%.4f", lat, lon)
+	}
+
+	var list nwsProductList
+	listURL := fmt.Sprintf("%s/products/types/AFD/locations/%s", c.NWSBaseURL, url.PathEscape(office))
+	if err := c.getURL(ctx, "AFD list", listURL, &list); err != nil {
+		return types.AFDresponse{}, err
+	}
+	if len(list.Graph) == 0 {
+		return types.AFDresponse{}, fmt.Errorf("no AFD issued by %s", office)
+	}
+
+	// the list is newest first
+	var afd types.AFDresponse
+	productURL := fmt.Sprintf("%s/products/%s", c.NWSBaseURL, url.PathEscape(list.Graph[0].ID))
+	if err := c.getURL(ctx, "AFD", productURL, &afd); err != nil {
+		return types.AFDresponse{}, err
+	}
+	return afd, nil
+}
+
+// GetAFD returns the latest-issued AFD across fixtures. Fixtures carry no
+// office boundaries, so the point is ignored. Like the live client it
+// isn't strict: api.weather.gov products carry more than is modelled.
+func (f *Fixture) GetAFD(ctx context.Context, lat, lon float64) (types.AFDresponse, error) {
+	lenient := *f
+	lenient.Strict = false
+
+	var records []types.AFDresponse
+	if err := lenient.load(ctx, "afd*.json", &records); err != nil {
+		return types.AFDresponse{}, err
+	}
+
+	var latest *types.AFDresponse
+	for i := range records {
+		if !strings.EqualFold(records[i].ProductCode, "AFD") {
+			continue
+		}
+		if latest == nil || records[i].IssuanceTime > latest.IssuanceTime {
+			latest = &records[i]
+		}
+	}
+	if latest == nil {
+		return types.AFDresponse{}, fmt.Errorf("no AFD data")
+	}
+	return *latest, nil
+}
@@ -0,0 +1,74 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+)
+
+// RecordError is one API record that didn't decode, named by its station
+type RecordError struct {
+	Station string
+	Err     error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Station, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// decodeRecords decodes a JSON array into out, a pointer to a slice, one
+// record at a time so a problem can be pinned to a station. Strict rejects
+// unknown fields and fails on any bad record, reporting all of them;
+// otherwise malformed records are dropped and counted in one warning.
+func decodeRecords(data []byte, out any, strict bool) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	slice := reflect.ValueOf(out).Elem()
+	slice.Set(reflect.MakeSlice(slice.Type(), 0, len(items)))
+	var errs []error
+	for i, item := range items {
+		record := reflect.New(slice.Type().Elem())
+		dec := json.NewDecoder(bytes.NewReader(item))
+		if strict {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(record.Interface()); err != nil {
+			errs = append(errs, &RecordError{Station: stationID(item, i), Err: err})
+			continue
+		}
+		slice.Set(reflect.Append(slice, record.Elem()))
+	}
+	if strict || len(errs) == 0 {
+		return errors.Join(errs...)
+	}
+
+	stations := make([]string, len(errs))
+	for i, err := range errs {
+		stations[i] = err.(*RecordError).Station
+	}
+	slog.Warn("Dropped malformed records", "dropped", len(errs), "of", len(items),
+		"stations", stations, "error", errors.Join(errs...))
+	return nil
+}
+
+// stationID names a raw record by its icaoId, or by position for products
+// that have none
+func stationID(item json.RawMessage, i int) string {
+	var id struct {
+		IcaoID string `json:"icaoId"`
+	}
+	if json.Unmarshal(item, &id) == nil && id.IcaoID != "" {
+		return id.IcaoID
+	}
+	return fmt.Sprintf("record %d", i)
+}
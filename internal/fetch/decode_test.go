@@ -0,0 +1,122 @@
+package fetch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// recordTypes are the slices the API products decode into
+var recordTypes = []func() any{
+	func() any { return &[]types.METARresponse{} },
+	func() any { return &[]types.TAFresponse{} },
+	func() any { return &[]types.PIREPresponse{} },
+	func() any { return &[]types.AirSigmetResponse{} },
+	func() any { return &[]types.GAirmetResponse{} },
+	func() any { return &[]types.AFDresponse{} },
+}
+
+func TestDecodeRecordsDropsBad(t *testing.T) {
+	data := []byte(`[{"icaoId":"KSGF","temp":12},{"icaoId":"KJLN","temp":"warm"},{"icaoId":"KBBG","extra":1}]`)
+
+	var lenient []types.METARresponse
+	if err := decodeRecords(data, &lenient, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(lenient) != 2 || lenient[0].IcaoID != "KSGF" || lenient[1].IcaoID != "KBBG" {
+		t.Errorf("lenient kept %+v", lenient)
+	}
+
+	var strict []types.METARresponse
+	err := decodeRecords(data, &strict, true)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("strict err = %v, want the joined record errors", err)
+	}
+	var stations []string
+	for _, e := range joined.Unwrap() {
+		var recordErr *RecordError
+		if errors.As(e, &recordErr) {
+			stations = append(stations, recordErr.Station)
+		}
+	}
+	if !reflect.DeepEqual(stations, []string{"KJLN", "KBBG"}) {
+		t.Errorf("strict reported %v (err %v)", stations, err)
+	}
+}
+
+// TestDecodeTestdataStrict keeps the record types in step with every field
+// the API sends; api.weather.gov's AFD carries more than is modelled and is
+// always decoded leniently
+func TestDecodeTestdataStrict(t *testing.T) {
+	files := map[string]func() any{
+		"metar*.json":     recordTypes[0],
+		"taf*.json":       recordTypes[1],
+		"pirep*.json":     recordTypes[2],
+		"airsigmet*.json": recordTypes[3],
+		"gairmet*.json":   recordTypes[4],
+	}
+	for pattern, newSlice := range files {
+		paths, _ := filepath.Glob(filepath.Join("../../testdata", pattern))
+		if len(paths) == 0 {
+			t.Errorf("no testdata for %s", pattern)
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := decodeRecords(data, newSlice(), true); err != nil {
+				t.Errorf("%s: %v", path, err)
+			}
+		}
+	}
+}
+
+func TestDecodeGust(t *testing.T) {
+	data := []byte(`[{"icaoId":"KAMA","wdir":210,"wspd":22,"wgst":"31","mostRecent":1,"prior":0,"metar_id":1}]`)
+	var records []types.METARresponse
+	if err := decodeRecords(data, &records, true); err != nil {
+		t.Fatal(err)
+	}
+	if gust := records[0].Wgst; gust == nil || *gust != 31 {
+		t.Errorf("Wgst = %v, want 31", gust)
+	}
+}
+
+func FuzzDecodeRecords(f *testing.F) {
+	paths, err := filepath.Glob("../../testdata/*.json")
+	if err != nil || len(paths) == 0 {
+		f.Fatalf("no testdata: %v", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, newSlice := range recordTypes {
+			strict, lenient := newSlice(), newSlice()
+			strictErr := decodeRecords(data, strict, true)
+			lenientErr := decodeRecords(data, lenient, false)
+
+			// lenient only fails when the array itself doesn't decode
+			if lenientErr != nil && strictErr == nil {
+				t.Fatalf("%T: lenient failed where strict didn't: %v", strict, lenientErr)
+			}
+			if strictErr == nil && !reflect.DeepEqual(strict, lenient) {
+				t.Fatalf("%T: strict and lenient records differ", strict)
+			}
+			if lenientErr == nil && reflect.ValueOf(lenient).Elem().Len() < reflect.ValueOf(strict).Elem().Len() {
+				t.Fatalf("%T: lenient kept fewer records than strict", strict)
+			}
+		}
+	})
+}
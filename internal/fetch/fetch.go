@@ -1,42 +1,122 @@
 package fetch
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"reflect"
 	"time"
 
 	"github.com/house-holder/pilot-bar/pkg/types"
 )
 
-const baseURL = "https://aviationweather.gov/api/data"
+const (
+	baseURL    = "https://aviationweather.gov/api/data"
+	nwsBaseURL = "https://api.weather.gov"
+	// api.weather.gov rejects requests without an identifying User-Agent
+	userAgent = "pilot-bar (github.com/house-holder/pilot-bar)"
+)
+
+// Client fetches from the aviationweather.gov data API. Every call honours
+// ctx and is bounded by Deadline across all of its attempts.
+type Client struct {
+	HTTP        *http.Client
+	BaseURL     string
+	NWSBaseURL  string // api.weather.gov, for products AWC doesn't carry
+	MaxAttempts int
+	BaseDelay   time.Duration // first backoff, doubled per attempt
+	MaxDelay    time.Duration // backoff cap; Retry-After may exceed it
+	Deadline    time.Duration // overall budget per call, retries included
+	Strict      bool          // fail on unknown or malformed fields
+}
 
-// FetchMETAR loads full report into a default-shaped struct
-func GetMETAR(icao string, maxAttempts int) (types.METARresponse, error) {
-	if maxAttempts < 1 {
-		maxAttempts = 1
+func NewClient() *Client {
+	return &Client{
+		HTTP:        &http.Client{Timeout: 10 * time.Second},
+		BaseURL:     baseURL,
+		NWSBaseURL:  nwsBaseURL,
+		MaxAttempts: 5,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    60 * time.Second,
+		Deadline:    2 * time.Minute,
 	}
+}
 
-	metarURL := fmt.Sprintf("%s/metar?ids=%s&format=json", baseURL, icao)
-	client := &http.Client{Timeout: 10 * time.Second}
-	startTime := time.Now()
+// GetMETAR loads full report into a default-shaped struct
+func (c *Client) GetMETAR(ctx context.Context, icao string) (types.METARresponse, error) {
+	query := url.Values{"ids": {icao}, "format": {"json"}}
 
 	var payload []types.METARresponse
-	err := doWithRetry(maxAttempts, func(attempt int) (bool, error) {
+	if err := c.getJSON(ctx, "METAR", "/metar", query, &payload); err != nil {
+		return types.METARresponse{}, err
+	}
+	if len(payload) == 0 {
+		return types.METARresponse{}, fmt.Errorf("no METAR data for %s", icao)
+	}
+	return payload[0], nil
+}
+
+// GetTAF loads the current TAF, preferring the one flagged most recent
+func (c *Client) GetTAF(ctx context.Context, icao string) (types.TAFresponse, error) {
+	query := url.Values{"ids": {icao}, "format": {"json"}}
+
+	var payload []types.TAFresponse
+	if err := c.getJSON(ctx, "TAF", "/taf", query, &payload); err != nil {
+		return types.TAFresponse{}, err
+	}
+	if len(payload) == 0 {
+		return types.TAFresponse{}, fmt.Errorf("no TAF data for %s", icao)
+	}
+	for _, taf := range payload {
+		if taf.MostRecent == 1 {
+			return taf, nil
+		}
+	}
+	return payload[0], nil
+}
+
+// getJSON performs GET BaseURL+path with retries and decodes into out
+func (c *Client) getJSON(ctx context.Context, product, path string, query url.Values, out any) error {
+	return c.getURL(ctx, product, fmt.Sprintf("%s%s?%s", c.BaseURL, path, query.Encode()), out)
+}
+
+// getURL performs GET reqURL with retries and decodes into out
+func (c *Client) getURL(ctx context.Context, product, reqURL string, out any) error {
+	if c.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Deadline)
+		defer cancel()
+	}
+
+	startTime := time.Now()
+
+	err := c.doWithRetry(ctx, func(attempt int) (bool, error) {
 		if attempt > 1 {
-			slog.Info(fmt.Sprintf("Fetch METAR retry (%d of %d)", attempt, maxAttempts))
+			slog.Info(fmt.Sprintf("Fetch %s retry (%d of %d)", product, attempt, c.MaxAttempts))
 		} else {
-			slog.Info("Fetching METAR")
+			slog.Info(fmt.Sprintf("Fetching %s", product))
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return false, fmt.Errorf("building request failed: %w", err)
 		}
+		req.Header.Set("User-Agent", userAgent)
 
-		resp, err := client.Get(metarURL)
+		resp, err := c.HTTP.Do(req)
 		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
 			var netErr net.Error
 			if errors.As(err, &netErr) && netErr.Timeout() {
-				slog.Warn("Fetch timeout", "attempt", attempt, "max", maxAttempts)
+				slog.Warn("Fetch timeout", "attempt", attempt, "max", c.MaxAttempts)
 				return true, err
 			}
 			return false, fmt.Errorf("HTTP request failed: %w", err)
@@ -45,59 +125,46 @@ func GetMETAR(icao string, maxAttempts int) (types.METARresponse, error) {
 
 		if statusRetryOK(resp.StatusCode) {
 			slog.Warn("OK to retry", "status", resp.Status, "attempt", attempt)
-			return true, fmt.Errorf("status %d: %s", resp.StatusCode, resp.Status)
+			return true, &StatusError{
+				Code:       resp.StatusCode,
+				Status:     resp.Status,
+				RetryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			}
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			return false, fmt.Errorf("status: %s", resp.Status)
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusNoContent:
+			// the API answers 204 when a query matches nothing; out is
+			// left as the caller made it, an empty slice or zero product
+			return false, nil
+		default:
+			return false, &StatusError{Code: resp.StatusCode, Status: resp.Status}
 		}
 
-		var decoded []types.METARresponse
-		if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
-			return false, fmt.Errorf("decode failed: %w", err)
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return false, fmt.Errorf("reading body failed: %w", err)
 		}
-		if len(decoded) == 0 {
-			return false, fmt.Errorf("no METAR data for %s", icao)
+		if err := c.decode(body, out); err != nil {
+			return false, fmt.Errorf("decode failed: %w", err)
 		}
-
-		payload = decoded
 		return false, nil
 	})
-
 	if err != nil {
-		return types.METARresponse{}, err
+		return err
 	}
 
 	fetchDuration := time.Since(startTime).Seconds()
 	slog.Info("Fetch OK", "took", fmt.Sprintf("%.3fs", fetchDuration))
-	return payload[0], nil
-}
-
-func doWithRetry(maxAttempts int, op func(attempt int) (bool, error)) error {
-	var lastErr error
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		retry, err := op(attempt)
-		if err == nil {
-			return nil
-		}
-
-		lastErr = err
-		if !retry || attempt == maxAttempts {
-			break
-		}
-		time.Sleep(2 * time.Second) // backoff delay
-	}
-
-	return lastErr
+	return nil
 }
 
-func statusRetryOK(code int) bool {
-	switch code {
-	case http.StatusRequestTimeout, http.StatusTooManyRequests,
-		http.StatusInternalServerError, http.StatusBadGateway,
-		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
-		return true
-	default:
-		return false
+// decode reads the aviationweather.gov arrays record by record; anything
+// else (api.weather.gov) is decoded whole
+func (c *Client) decode(data []byte, out any) error {
+	if reflect.TypeOf(out).Elem().Kind() == reflect.Slice {
+		return decodeRecords(data, out, c.Strict)
 	}
+	return json.Unmarshal(data, out)
 }
@@ -0,0 +1,275 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/mockawc"
+)
+
+// testClient talks to a mock API over the testdata fixtures with backoff
+// short enough that retries don't slow the tests down
+func testClient(t *testing.T) (*Client, *mockawc.Server) {
+	t.Helper()
+	srv := mockawc.New("../../testdata")
+	t.Cleanup(srv.Close)
+
+	c := NewClient()
+	c.HTTP = srv.Client()
+	c.BaseURL = srv.BaseURL()
+	c.BaseDelay = 10 * time.Millisecond
+	c.MaxDelay = 40 * time.Millisecond
+	c.Deadline = 5 * time.Second
+	return c, srv
+}
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(m.Run())
+}
+
+func statusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	return 0
+}
+
+func TestGetMETAROK(t *testing.T) {
+	c, srv := testClient(t)
+	metar, err := c.GetMETAR(context.Background(), "KSGF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metar.IcaoID != "KSGF" {
+		t.Errorf("IcaoID = %q", metar.IcaoID)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
+
+func TestRetryAfter429(t *testing.T) {
+	c, srv := testClient(t)
+	srv.Fail("metar", mockawc.TooManyRequests("1"))
+
+	start := time.Now()
+	if _, err := c.GetMETAR(context.Background(), "KSGF"); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("retried after %v, before Retry-After", waited)
+	}
+	if hits := srv.Hits("metar"); hits != 2 {
+		t.Errorf("hits = %d, want 2", hits)
+	}
+}
+
+func TestRetryAfterBeyondDeadline(t *testing.T) {
+	c, srv := testClient(t)
+	c.Deadline = time.Second
+	srv.Fail("metar", mockawc.TooManyRequests("30"))
+
+	_, err := c.GetMETAR(context.Background(), "KSGF")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("err = %v, want a StatusError", err)
+	}
+	if statusErr.Code != http.StatusTooManyRequests || statusErr.RetryAfter != 30*time.Second {
+		t.Errorf("StatusError = %+v", statusErr)
+	}
+	if !strings.Contains(err.Error(), "no time left to retry") {
+		t.Errorf("err = %v", err)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
+
+func TestRetry5xx(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    []mockawc.Failure
+		maxAttempts int
+		wantHits    int
+		wantCode    int // 0 for success
+	}{
+		{"recovers", []mockawc.Failure{mockawc.Status(500), mockawc.Status(502), mockawc.Unavailable("")}, 5, 4, 0},
+		{"exhausted", []mockawc.Failure{mockawc.Status(500), mockawc.Status(500), mockawc.Status(500)}, 3, 3, 500},
+		{"gateway timeout", []mockawc.Failure{mockawc.Status(504)}, 1, 1, 504},
+		{"not retryable", []mockawc.Failure{mockawc.Status(404)}, 5, 1, 404},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, srv := testClient(t)
+			c.MaxAttempts = tt.maxAttempts
+			srv.Fail("metar", tt.failures...)
+
+			start := time.Now()
+			_, err := c.GetMETAR(context.Background(), "KSGF")
+			if code := statusCode(err); code != tt.wantCode {
+				t.Errorf("status = %d, want %d (err %v)", code, tt.wantCode, err)
+			}
+			if tt.wantCode == 0 && err != nil {
+				t.Errorf("err = %v", err)
+			}
+			if hits := srv.Hits("metar"); hits != tt.wantHits {
+				t.Errorf("hits = %d, want %d", hits, tt.wantHits)
+			}
+			// each wait is at least half of the doubled BaseDelay
+			minWait := time.Duration(0)
+			for attempt := 1; attempt < tt.wantHits; attempt++ {
+				minWait += min(c.BaseDelay<<(attempt-1), c.MaxDelay) / 2
+			}
+			if waited := time.Since(start); waited < minWait {
+				t.Errorf("took %v, want at least %v of backoff", waited, minWait)
+			}
+		})
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	c := &Client{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 8; attempt++ {
+		full := min(c.BaseDelay<<(attempt-1), c.MaxDelay)
+		for range 20 {
+			if d := c.backoff(attempt); d < full/2 || d > full {
+				t.Fatalf("backoff(%d) = %v, want %v..%v", attempt, d, full/2, full)
+			}
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	c, srv := testClient(t)
+	c.HTTP.Timeout = 100 * time.Millisecond
+	srv.Fail("metar", mockawc.Timeout(5*time.Second))
+
+	if _, err := c.GetMETAR(context.Background(), "KSGF"); err != nil {
+		t.Fatalf("timed out request wasn't retried: %v", err)
+	}
+	if hits := srv.Hits("metar"); hits != 2 {
+		t.Errorf("hits = %d, want 2", hits)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	c, srv := testClient(t)
+	c.Deadline = 200 * time.Millisecond
+	srv.Fail("metar", mockawc.Timeout(5*time.Second))
+
+	start := time.Now()
+	_, err := c.GetMETAR(context.Background(), "KSGF")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+	if statusCode(err) != 0 {
+		t.Errorf("err = %v, want no StatusError", err)
+	}
+	if waited := time.Since(start); waited > 2*time.Second {
+		t.Errorf("call outlived its Deadline: %v", waited)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
+
+func TestEmptyBody(t *testing.T) {
+	c, srv := testClient(t)
+	srv.Fail("metar", mockawc.Empty())
+
+	_, err := c.GetMETAR(context.Background(), "KSGF")
+	if err == nil || !strings.Contains(err.Error(), "no METAR data for KSGF") {
+		t.Errorf("err = %v", err)
+	}
+	if statusCode(err) != 0 {
+		t.Errorf("err = %v, want no StatusError", err)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
+
+func TestMalformedJSON(t *testing.T) {
+	c, srv := testClient(t)
+	srv.Fail("metar", mockawc.Malformed())
+
+	_, err := c.GetMETAR(context.Background(), "KSGF")
+	if err == nil || !strings.Contains(err.Error(), "decode failed") {
+		t.Errorf("err = %v", err)
+	}
+	if statusCode(err) != 0 {
+		t.Errorf("err = %v, want no StatusError", err)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1 (a bad body isn't retried)", hits)
+	}
+}
+
+func TestCancelDuringBackoff(t *testing.T) {
+	c, srv := testClient(t)
+	c.Deadline = 0 // only the cancel ends the wait
+	srv.Fail("metar", mockawc.Unavailable("30"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	_, err := c.GetMETAR(ctx, "KSGF")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want canceled", err)
+	}
+	if code := statusCode(err); code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the last status reported (err %v)", code, err)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
+
+func TestCancelDuringRequest(t *testing.T) {
+	c, srv := testClient(t)
+	srv.Fail("metar", mockawc.Timeout(5*time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := c.GetMETAR(ctx, "KSGF")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want canceled", err)
+	}
+	if waited := time.Since(start); waited > 2*time.Second {
+		t.Errorf("cancel took %v to take effect", waited)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
+
+func TestNoContent(t *testing.T) {
+	c, srv := testClient(t)
+	_, err := c.GetMETAR(context.Background(), "KXXX")
+	if err == nil || !strings.Contains(err.Error(), "no METAR data for KXXX") {
+		t.Errorf("err = %v", err)
+	}
+	if hits := srv.Hits("metar"); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+
+	// api.weather.gov products decode into a struct, which "[]" can't fill
+	nws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(nws.Close)
+	c.NWSBaseURL = nws.URL
+	_, err = c.GetAFD(context.Background(), 37.24, -93.39)
+	if err == nil || !strings.Contains(err.Error(), "no NWS office covers") {
+		t.Errorf("err = %v, want no office rather than a decode error", err)
+	}
+}
@@ -0,0 +1,109 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// Fixture serves products from API-shaped JSON files on disk, e.g. the ones
+// in testdata/. Each product reads every file matching its glob, so
+// metar.json and metar_long.json are both searched.
+type Fixture struct {
+	Dir    string
+	Strict bool // fail on unknown or malformed fields
+}
+
+func NewFixture(dir string) (*Fixture, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("fixture dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("fixture dir: %s is not a directory", dir)
+	}
+	return &Fixture{Dir: dir}, nil
+}
+
+// GetMETAR returns the most recent observation for icao across fixtures
+func (f *Fixture) GetMETAR(ctx context.Context, icao string) (types.METARresponse, error) {
+	var records []types.METARresponse
+	if err := f.load(ctx, "metar*.json", &records); err != nil {
+		return types.METARresponse{}, err
+	}
+
+	var latest *types.METARresponse
+	for i := range records {
+		if !strings.EqualFold(records[i].IcaoID, icao) {
+			continue
+		}
+		if latest == nil || records[i].ObsTime > latest.ObsTime {
+			latest = &records[i]
+		}
+	}
+	if latest == nil {
+		return types.METARresponse{}, fmt.Errorf("no METAR data for %s", icao)
+	}
+	return *latest, nil
+}
+
+// GetTAF returns the latest-issued TAF for icao across fixtures
+func (f *Fixture) GetTAF(ctx context.Context, icao string) (types.TAFresponse, error) {
+	var records []types.TAFresponse
+	if err := f.load(ctx, "taf*.json", &records); err != nil {
+		return types.TAFresponse{}, err
+	}
+
+	var latest *types.TAFresponse
+	for i := range records {
+		if !strings.EqualFold(records[i].IcaoID, icao) {
+			continue
+		}
+		if latest == nil || records[i].IssueTime > latest.IssueTime {
+			latest = &records[i]
+		}
+	}
+	if latest == nil {
+		return types.TAFresponse{}, fmt.Errorf("no TAF data for %s", icao)
+	}
+	return *latest, nil
+}
+
+// load decodes every JSON array matching pattern and appends the elements
+// to out, which must point to a slice
+func (f *Fixture) load(ctx context.Context, pattern string, out any) error {
+	paths, err := filepath.Glob(filepath.Join(f.Dir, pattern))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no fixture matching %s in %s", pattern, f.Dir)
+	}
+
+	var merged []json.RawMessage
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return fmt.Errorf("fixture %s: %w", path, err)
+		}
+		merged = append(merged, items...)
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+	return decodeRecords(data, out, f.Strict)
+}
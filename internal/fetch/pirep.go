@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/house-holder/pilot-bar/internal/geo"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// PIREPQuery selects reports around a point. The API is asked for the
+// bounding box; callers filter by true distance and altitude.
+type PIREPQuery struct {
+	Lat, Lon float64
+	RadiusNM float64
+	AgeHours int
+}
+
+func (q PIREPQuery) bbox() (minLat, minLon, maxLat, maxLon float64) {
+	return geo.BBox(q.Lat, q.Lon, q.RadiusNM)
+}
+
+func (q PIREPQuery) contains(lat, lon float64) bool {
+	minLat, minLon, maxLat, maxLon := q.bbox()
+	return lat >= minLat && lat <= maxLat && lon >= minLon && lon <= maxLon
+}
+
+// GetPIREPs loads recent reports within the query's bounding box
+func (c *Client) GetPIREPs(ctx context.Context, q PIREPQuery) ([]types.PIREPresponse, error) {
+	minLat, minLon, maxLat, maxLon := q.bbox()
+	query := url.Values{
+		"format": {"json"},
+		"bbox":   {fmt.Sprintf("%.4f,%.4f,%.4f,%.4f", minLat, minLon, maxLat, maxLon)},
+	}
+	if q.AgeHours > 0 {
+		query.Set("age", strconv.Itoa(q.AgeHours))
+	}
+
+	var payload []types.PIREPresponse
+	if err := c.getJSON(ctx, "PIREP", "/pirep", query, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// GetPIREPs returns fixture reports within the query's bounding box. Age is
+// ignored so recorded fixtures stay usable.
+func (f *Fixture) GetPIREPs(ctx context.Context, q PIREPQuery) ([]types.PIREPresponse, error) {
+	var records []types.PIREPresponse
+	if err := f.load(ctx, "pirep*.json", &records); err != nil {
+		return nil, err
+	}
+
+	var matched []types.PIREPresponse
+	for _, record := range records {
+		if q.contains(record.Lat, record.Long) {
+			matched = append(matched, record)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,101 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatusError is a non-200 response. RetryAfter is set from the header on
+// retryable statuses and is zero when absent or unparseable.
+type StatusError struct {
+	Code       int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status: %s", e.Status)
+}
+
+// doWithRetry runs op until it succeeds, reports a permanent error, runs out
+// of attempts, or ctx ends. Waits never outlast ctx's deadline.
+func (c *Client) doWithRetry(ctx context.Context, op func(attempt int) (bool, error)) error {
+	maxAttempts := max(c.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		retry, err := op(attempt)
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		if !retry || attempt == maxAttempts {
+			break
+		}
+
+		delay := c.backoff(attempt)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("no time left to retry: %w", lastErr)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %w)", ctx.Err(), lastErr)
+		case <-timer.C:
+		}
+	}
+
+	return lastErr
+}
+
+// backoff doubles BaseDelay per attempt up to MaxDelay, then keeps a random
+// half of it so that many clients don't retry in lockstep
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func statusRetryOK(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter reads a Retry-After header in either delay-seconds or
+// HTTP-date form
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
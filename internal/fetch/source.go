@@ -0,0 +1,28 @@
+package fetch
+
+import (
+	"context"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+const (
+	SourceAWC     = "awc"
+	SourceFixture = "fixture"
+)
+
+// Source provides raw products for a station or area. *Client is the live
+// aviationweather.gov backend and *Fixture the offline one.
+type Source interface {
+	GetMETAR(ctx context.Context, icao string) (types.METARresponse, error)
+	GetTAF(ctx context.Context, icao string) (types.TAFresponse, error)
+	GetPIREPs(ctx context.Context, q PIREPQuery) ([]types.PIREPresponse, error)
+	GetAFD(ctx context.Context, lat, lon float64) (types.AFDresponse, error)
+	GetAirSigmets(ctx context.Context) ([]types.AirSigmetResponse, error)
+	GetGAirmets(ctx context.Context) ([]types.GAirmetResponse, error)
+}
+
+var (
+	_ Source = (*Client)(nil)
+	_ Source = (*Fixture)(nil)
+)
@@ -0,0 +1,17 @@
+package forecast
+
+import (
+	"github.com/house-holder/pilot-bar/internal/category"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// Ceiling is the lowest broken/overcast layer or vertical visibility
+func Ceiling(c Conditions) *types.Feet {
+	return category.Ceiling(c.Clouds, c.VertVis)
+}
+
+// Category applies the FAA ceiling/visibility thresholds; elements the
+// TAF doesn't give don't lower the category
+func Category(c Conditions) string {
+	return category.FAA.Classify(Ceiling(c), c.Visibility)
+}
@@ -0,0 +1,175 @@
+// 'forecast' answers "what will it be at time T" from a decoded TAF by
+// layering BASE/FM, BECMG and TEMPO/PROB groups the way they're briefed.
+package forecast
+
+import (
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/category"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// Conditions are the forecast elements in effect at one moment
+type Conditions struct {
+	Wind       *types.WindData   `json:"wind"`
+	Visibility *types.Visibility `json:"visibility"`
+	Clouds     []types.CloudData `json:"clouds"`
+	VertVis    *types.Feet       `json:"vertVis"`
+	WxString   string            `json:"wxString"`
+	WindShear  *types.WindShear  `json:"windShear"`
+	FltCat     string            `json:"fltCat"`
+}
+
+// Resolved holds the prevailing conditions at a time and the worst case
+// once temporary groups and unfinished BECMG transitions are considered
+type Resolved struct {
+	Time       int64             `json:"time"`
+	Prevailing Conditions        `json:"prevailing"`
+	Worst      Conditions        `json:"worst"`
+	WorstFrom  *types.TAFPeriod  `json:"worstFrom"` // group behind Worst, nil if prevailing
+	Active     []types.TAFPeriod `json:"active"`    // TEMPO/PROB/BECMG in play
+}
+
+// At resolves the TAF at t; ok is false outside the valid period
+func At(taf types.TAF, t time.Time) (Resolved, bool) {
+	at := t.Unix()
+	if at < taf.ValidFrom || at >= taf.ValidTo {
+		return Resolved{}, false
+	}
+
+	governing := -1
+	for i, period := range taf.Periods {
+		if (period.Change == "BASE" || period.Change == "FM") && period.From <= at {
+			governing = i
+		}
+	}
+	if governing == -1 {
+		return Resolved{}, false
+	}
+
+	prevailing := apply(Conditions{}, taf.Periods[governing])
+	var overlays []types.TAFPeriod
+
+	for i, period := range taf.Periods {
+		if period.From > at {
+			continue
+		}
+		switch period.Change {
+		case "BECMG":
+			if i < governing {
+				continue // superseded by the FM group
+			}
+			if at >= period.Becoming {
+				prevailing = apply(prevailing, period)
+			} else {
+				overlays = append(overlays, period)
+			}
+		case "TEMPO", "PROB":
+			if at < period.To {
+				overlays = append(overlays, period)
+			}
+		}
+	}
+
+	prevailing.FltCat = Category(prevailing)
+	resolved := Resolved{
+		Time:       at,
+		Prevailing: prevailing,
+		Worst:      prevailing,
+		Active:     overlays,
+	}
+
+	for _, period := range overlays {
+		conditions := apply(prevailing, period)
+		conditions.FltCat = Category(conditions)
+		if category.WorseThan(conditions.FltCat, resolved.Worst.FltCat) {
+			resolved.Worst = conditions
+			resolved.WorstFrom = &period
+		}
+	}
+	return resolved, true
+}
+
+// Hourly resolves the TAF on each hour from start (rounded down) for n
+// hours, skipping hours outside the valid period
+func Hourly(taf types.TAF, start time.Time, n int) []Resolved {
+	var out []Resolved
+	hour := start.UTC().Truncate(time.Hour)
+	for i := 0; i < n; i++ {
+		if resolved, ok := At(taf, hour.Add(time.Duration(i)*time.Hour)); ok {
+			out = append(out, resolved)
+		}
+	}
+	return out
+}
+
+// apply overlays the elements a group forecasts onto base. BASE/FM groups
+// replace everything; the others only what they mention.
+func apply(base Conditions, period types.TAFPeriod) Conditions {
+	if period.Change == "BASE" || period.Change == "FM" {
+		base = Conditions{}
+	}
+
+	if period.Wind != nil {
+		base.Wind = period.Wind
+	}
+	if period.Visibility != nil {
+		base.Visibility = period.Visibility
+	}
+	if period.Clouds != nil {
+		base.Clouds = period.Clouds
+		base.VertVis = nil
+	}
+	if period.VertVis != nil {
+		base.VertVis = period.VertVis
+		if period.Clouds == nil {
+			base.Clouds = nil
+		}
+	}
+	switch period.WxString {
+	case "":
+	case "NSW":
+		base.WxString = ""
+	default:
+		base.WxString = period.WxString
+	}
+	if period.WindShear != nil {
+		base.WindShear = period.WindShear
+	}
+	return base
+}
+
+// Overlap is a temporary or transitional group that brings IFR or worse
+type Overlap struct {
+	Period types.TAFPeriod
+	FltCat string
+}
+
+// IFRDuring lists the TEMPO/PROB/BECMG groups forecasting IFR or LIFR at
+// any point between from and to
+func IFRDuring(taf types.TAF, from, to time.Time) []Overlap {
+	var out []Overlap
+	for _, period := range taf.Periods {
+		if period.Change != "TEMPO" && period.Change != "PROB" && period.Change != "BECMG" {
+			continue
+		}
+		end := period.To
+		if period.Change == "BECMG" {
+			end = period.Becoming
+		}
+		if period.From >= to.Unix() || end <= from.Unix() {
+			continue
+		}
+
+		at := time.Unix(max(period.From, from.Unix()), 0)
+		resolved, ok := At(taf, at)
+		if !ok {
+			continue
+		}
+		conditions := apply(resolved.Prevailing, period)
+		if fltCat := Category(conditions); !category.WorseThan("IFR", fltCat) {
+			out = append(out, Overlap{Period: period, FltCat: fltCat})
+		}
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/parse"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+const testTAF = "TAF KSGF 251720Z 2518/2618 12008KT P6SM SCT050 " +
+	"BECMG 2520/2522 18012KT -RA BKN025 " +
+	"TEMPO 2600/2604 2SM -SHRA BKN015 " +
+	"FM260600 27015G25KT P6SM OVC040 " +
+	"PROB30 2610/2614 1SM TSRA OVC008CB"
+
+func at(day, hour int) time.Time {
+	return time.Date(2025, 10, day, hour, 0, 0, 0, time.UTC)
+}
+
+func testdataTAF(t *testing.T) types.TAF {
+	t.Helper()
+	taf, err := parse.ParseTAF(testTAF, at(25, 17))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return taf
+}
+
+func TestAt(t *testing.T) {
+	taf := testdataTAF(t)
+	tests := []struct {
+		name       string
+		t          time.Time
+		wind       types.DegTrue
+		ceiling    types.Feet // 0 for none
+		prevailing string
+		worst      string
+		worstFrom  string // change of the group behind Worst
+		wx         string
+	}{
+		{"base", at(25, 19), 120, 0, "VFR", "VFR", "", ""},
+		{"during BECMG the new conditions are the worst case", at(25, 21), 120, 0, "VFR", "MVFR", "BECMG", ""},
+		{"after BECMG", at(25, 23), 180, 2500, "MVFR", "MVFR", "", "-RA"},
+		{"TEMPO overlay", at(26, 2), 180, 2500, "MVFR", "IFR", "TEMPO", "-RA"},
+		{"TEMPO over", at(26, 4), 180, 2500, "MVFR", "MVFR", "", "-RA"},
+		{"FM replaces everything", at(26, 7), 270, 4000, "VFR", "VFR", "", ""},
+		{"PROB overlay", at(26, 12), 270, 4000, "VFR", "IFR", "PROB", ""},
+	}
+	for _, tt := range tests {
+		resolved, ok := At(taf, tt.t)
+		if !ok {
+			t.Fatalf("%s: not resolved", tt.name)
+		}
+		p := resolved.Prevailing
+		if p.Wind == nil || p.Wind.Direction != tt.wind {
+			t.Errorf("%s: prevailing wind %+v, want %03d", tt.name, p.Wind, tt.wind)
+		}
+		ceiling := Ceiling(p)
+		if ceiling == nil && tt.ceiling != 0 || ceiling != nil && *ceiling != tt.ceiling {
+			t.Errorf("%s: prevailing ceiling %v, want %d", tt.name, ceiling, tt.ceiling)
+		}
+		if p.FltCat != tt.prevailing || resolved.Worst.FltCat != tt.worst {
+			t.Errorf("%s: %s/%s, want %s/%s", tt.name, p.FltCat, resolved.Worst.FltCat, tt.prevailing, tt.worst)
+		}
+		from := ""
+		if resolved.WorstFrom != nil {
+			from = resolved.WorstFrom.Change
+		}
+		if from != tt.worstFrom {
+			t.Errorf("%s: worst from %q, want %q", tt.name, from, tt.worstFrom)
+		}
+		if p.WxString != tt.wx {
+			t.Errorf("%s: prevailing weather %q, want %q", tt.name, p.WxString, tt.wx)
+		}
+	}
+
+	// the TEMPO's weather and visibility are overlaid, the prevailing wind kept
+	resolved, _ := At(taf, at(26, 2))
+	if w := resolved.Worst; w.WxString != "-SHRA" || w.Visibility.Miles != 2 || w.Wind.Direction != 180 {
+		t.Errorf("TEMPO worst case = %+v", w)
+	}
+	// FM leaves nothing of the earlier groups
+	resolved, _ = At(taf, at(26, 7))
+	if w := resolved.Prevailing.Wind; w.Gusts == nil || *w.Gusts != 25 || resolved.Prevailing.Visibility.Plus != true {
+		t.Errorf("FM conditions = %+v", resolved.Prevailing)
+	}
+
+	for _, outside := range []time.Time{at(25, 17), at(26, 18)} {
+		if _, ok := At(taf, outside); ok {
+			t.Errorf("resolved at %v, outside the valid period", outside)
+		}
+	}
+}
+
+func TestHourly(t *testing.T) {
+	taf := testdataTAF(t)
+	// from mid-hour, rounded down; the last six hours run past the TAF
+	hours := Hourly(taf, at(25, 18).Add(30*time.Minute), 30)
+	if len(hours) != 24 {
+		t.Fatalf("%d hours, want 24", len(hours))
+	}
+	if hours[0].Time != at(25, 18).Unix() {
+		t.Errorf("first hour %v", time.Unix(hours[0].Time, 0).UTC())
+	}
+
+	worst := ""
+	for _, h := range hours {
+		worst += h.Worst.FltCat[:1]
+	}
+	// 18-19 VFR, 20-21 BECMG MVFR, 22-23 MVFR, 00-03 TEMPO IFR,
+	// 04-05 MVFR, 06-09 VFR, 10-13 PROB IFR, 14-17 VFR
+	const want = "VVMMMMIIIIMMVVVVIIIIVVVV"
+	if worst != want {
+		t.Errorf("worst categories %s, want %s", worst, want)
+	}
+}
+
+func TestIFRDuring(t *testing.T) {
+	taf := testdataTAF(t)
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{"BECMG brings only MVFR", at(25, 19), at(25, 23), nil},
+		{"TEMPO", at(25, 23), at(26, 1), []string{"TEMPO IFR"}},
+		{"PROB at the end of the window", at(26, 6), at(26, 11), []string{"PROB IFR"}},
+		{"both", at(25, 18), at(26, 18), []string{"TEMPO IFR", "PROB IFR"}},
+		{"window ends as the TEMPO starts", at(25, 22), at(26, 0), nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, o := range IFRDuring(taf, tt.from, tt.to) {
+			got = append(got, o.Period.Change+" "+o.FltCat)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package format
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// ActiveAdvisories are the cached advisories in effect at now
+func ActiveAdvisories(area types.AdvisoryArea, now time.Time) []types.Advisory {
+	var active []types.Advisory
+	for _, advisory := range area.Advisories {
+		if advisory.ValidFrom <= now.Unix() && now.Unix() < advisory.ValidTo {
+			active = append(active, advisory)
+		}
+	}
+	return active
+}
+
+// IsSIGMET - SIGMETs mark hazards to all aircraft, AIRMETs mainly to light
+// aircraft
+func IsSIGMET(advisory types.Advisory) bool {
+	return strings.HasSuffix(advisory.Product, "SIGMET")
+}
+
+// summarizeAdvisories renders each active advisory, e.g.
+// "CONVECTIVE SIGMET to FL450 until 1955Z" or
+// "G-AIRMET TURB SFC-12000ft until 1930Z, 14nm"; short lists the hazards
+func summarizeAdvisories(area types.AdvisoryArea, now time.Time, opts options) (string, bool) {
+	active := ActiveAdvisories(area, now)
+	if len(active) == 0 {
+		return "", false
+	}
+
+	if opts["short"] {
+		var hazards []string
+		for _, advisory := range active {
+			if !slices.Contains(hazards, advisory.Hazard) {
+				hazards = append(hazards, advisory.Hazard)
+			}
+		}
+		return strings.Join(hazards, " "), true
+	}
+
+	items := make([]string, 0, len(active))
+	for _, advisory := range active {
+		label := advisory.Product + " " + advisory.Hazard
+		if advisory.Product == "CONVECTIVE SIGMET" {
+			label = advisory.Product
+		}
+		if band := altitudeBand(advisory); band != "" {
+			label += " " + band
+		}
+		label += " until " + time.Unix(advisory.ValidTo, 0).UTC().Format("1504Z")
+		if !advisory.Inside {
+			label += fmt.Sprintf(", %.0fnm", advisory.DistanceNM)
+		}
+		items = append(items, label)
+	}
+	return joinList(items, opts), true
+}
+
+func altitudeBand(advisory types.Advisory) string {
+	base, top := "SFC", ""
+	switch {
+	case advisory.BaseFZL:
+		base = "FZL"
+	case advisory.Base != nil:
+		base = altitudeLabel(*advisory.Base)
+	}
+	if advisory.Top != nil {
+		top = altitudeLabel(*advisory.Top)
+	}
+
+	switch {
+	case top == "" && base == "SFC":
+		return ""
+	case top == "":
+		return "above " + base
+	default:
+		return base + "-" + top
+	}
+}
+
+func altitudeLabel(height types.Feet) string {
+	if height >= 18000 {
+		return fmt.Sprintf("FL%03d", height/100)
+	}
+	return fmt.Sprintf("%dft", height)
+}
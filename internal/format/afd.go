@@ -0,0 +1,43 @@
+package format
+
+import (
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// AFDNewFor is how long after the daemon first sees an issuance that it
+// counts as new
+const AFDNewFor = time.Hour
+
+// AFDIsNew reports whether the cached discussion arrived recently
+func AFDIsNew(afd types.AFD, now time.Time) bool {
+	if afd.Received == 0 {
+		return false
+	}
+	return now.Sub(time.Unix(afd.Received, 0)) < AFDNewFor
+}
+
+// afdSection renders one section; flat joins the product's wrapped lines
+// into paragraphs
+func afdSection(afd types.AFD, name string, opts options) (string, bool) {
+	section, ok := afd.Section(name)
+	if !ok || section.Text == "" {
+		return "", false
+	}
+	if !opts["flat"] {
+		return section.Text, true
+	}
+
+	var paragraphs []string
+	for paragraph := range strings.SplitSeq(section.Text, "\n\n") {
+		paragraphs = append(paragraphs, strings.Join(strings.Fields(paragraph), " "))
+	}
+	return strings.Join(paragraphs, "\n"), true
+}
+
+// FullAFD is the whole discussion as issued, for reading outside the bar
+func FullAFD(afd types.AFD) string {
+	return strings.TrimSpace(afd.Raw)
+}
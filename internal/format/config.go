@@ -0,0 +1,40 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/house-holder/pilot-bar/internal/config"
+)
+
+// Templates holds the compiled bar text and tooltip
+type Templates struct {
+	Text    *Template
+	Tooltip *Template
+}
+
+// FromConfig compiles the configured templates, using defaults where unset
+func FromConfig(cfg config.FormatCfg) (Templates, error) {
+	textSrc, tooltipSrc := cfg.Text, cfg.Tooltip
+	if textSrc == "" {
+		textSrc = DefaultText
+	}
+	if tooltipSrc == "" {
+		tooltipSrc = DefaultTooltip
+	}
+
+	text, err := Parse(textSrc)
+	if err != nil {
+		return Templates{}, fmt.Errorf("text format: %w", err)
+	}
+	tooltip, err := Parse(tooltipSrc)
+	if err != nil {
+		return Templates{}, fmt.Errorf("tooltip format: %w", err)
+	}
+	return Templates{Text: text, Tooltip: tooltip}, nil
+}
+
+// Defaults returns the built-in templates, which always compile
+func Defaults() Templates {
+	t, _ := FromConfig(config.FormatCfg{})
+	return t
+}
@@ -0,0 +1,413 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+const (
+	DefaultText    = "{icao} {fltcat} {age}"
+	DefaultTooltip = "{icao}[ ({name})]\n" +
+		"Observed:  {obs} ({age} ago)\n" +
+		"Wind:      {wind}\n" +
+		"[Runway:    {runway_wind}\n]" +
+		"[Vis:       {vis}\n]" +
+		"[Weather:   {wx}\n]" +
+		"Clouds:    {clouds}\n" +
+		"Temp/Dew:  {temp}°C / {dewp}°C\n" +
+		"Altimeter: {altim} inHg\n" +
+		"[Density:   {density_alt} ft (PA {press_alt} ft, RH {rh}%)\n]" +
+		"[Next:      {taf_next}\n]" +
+		"[Departure: {departure}\n]" +
+		"[Below minimums:\n{minimums:lines}\n]" +
+		"[Advisory:  {advisories:lines}\n]" +
+		"[PIREPs:    {pireps}\n]" +
+		"[{afd_new}\n]" +
+		"[Aviation discussion:\n{afd_aviation}\n]" +
+		"[Forecast:\n{taf_hourly}\n]" +
+		"[{remarks:lines}\n]" +
+		"{raw}"
+)
+
+// Data is everything a template can draw from
+type Data struct {
+	Airport types.Airport
+	Now     time.Time
+}
+
+func (d Data) age() time.Duration {
+	age := d.Now.Sub(time.Unix(d.Airport.METAR.Reported.Epoch, 0))
+	if age < 0 {
+		return 0
+	}
+	return age
+}
+
+type fieldFunc func(d Data, opts options) (string, bool)
+
+// fields, with the options each one understands beyond upper/lower:
+//
+//	icao, name, raw
+//	fltcat
+//	age       min: whole minutes only
+//	obs       local: local HH:MM instead of DDHHMMZ
+//	wind      raw: METAR group (12010G18KT), mag: magnetic direction
+//	wdir      mag: magnetic
+//	wspd, gust
+//	best_rwy  runway end best aligned with the wind
+//	xwind     crosswind on best_rwy (8 or 8G12), headwind: negative is a tailwind
+//	runway_wind  components on best_rwy
+//	runways   components on every runway end, lines: one per line
+//	vis       prevailing visibility, e.g. 1 3/4SM, metric: meters/km
+//	wx        present weather as text, lines: one group per line
+//	wx_raw    present weather as reported
+//	wx_icon   glyph for the most significant weather
+//	clouds    lines: one layer per line
+//	ceiling
+//	temp/dewp f: Fahrenheit, exact: tenths
+//	spread    exact: tenths
+//	altim     hpa: hectopascals
+//	press_alt, density_alt  feet
+//	rh        relative humidity, percent
+//	cu_base   estimated convective cloud base, feet AGL
+//	frz_level estimated freezing level, feet MSL
+//	remarks   lines: one remark per line
+//	taf_next  next TAF change group after now
+//	taf_raw
+//	taf_hourly  N: hours to show (default 12), compact: single line
+//	taf_temps   forecast max/min temperatures, f: Fahrenheit
+//	departure   departure check from the daemon's --depart
+//	minimums    personal minimums busted, lines: one per line
+//	pireps      turbulence/icing summary, raw: one report per line
+//	advisories  active AIRMET/SIGMETs, lines: one per line, short: hazards only
+//	afd_aviation  AVIATION section of the AFD, flat: unwrap lines
+//	afd_new       "New AFD issued HHMMZ" for an hour after it arrives
+var fields = map[string]fieldFunc{
+	"icao": func(d Data, _ options) (string, bool) {
+		return d.Airport.ICAO, d.Airport.ICAO != ""
+	},
+	"name": func(d Data, _ options) (string, bool) {
+		return d.Airport.Name, d.Airport.Name != ""
+	},
+	"raw": func(d Data, _ options) (string, bool) {
+		return d.Airport.METAR.Raw, d.Airport.METAR.Raw != ""
+	},
+	"fltcat": func(d Data, _ options) (string, bool) {
+		if fltCat := d.Airport.METAR.Category(); fltCat != "" {
+			return fltCat, true
+		}
+		return "UNK", true
+	},
+	"age": func(d Data, opts options) (string, bool) {
+		if d.Airport.METAR.Reported.Epoch == 0 {
+			return "", false
+		}
+		minutes := int(d.age().Minutes())
+		switch {
+		case opts["min"]:
+			return fmt.Sprintf("%d", minutes), true
+		case minutes < 60:
+			return fmt.Sprintf("%dm", minutes), true
+		default:
+			return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60), true
+		}
+	},
+	"obs": func(d Data, opts options) (string, bool) {
+		if d.Airport.METAR.Reported.Epoch == 0 {
+			return "", false
+		}
+		obs := time.Unix(d.Airport.METAR.Reported.Epoch, 0)
+		if opts["local"] {
+			return obs.Local().Format("15:04"), true
+		}
+		return obs.UTC().Format("021504Z"), true
+	},
+	"wind": func(d Data, opts options) (string, bool) {
+		w := d.Airport.METAR.Wind
+		if opts["mag"] && !opts["raw"] && !w.Calm && !w.Variable {
+			if w.DirectionMag == nil {
+				return "", false
+			}
+			return formatWindMag(w), true
+		}
+		return formatWind(w, opts["raw"]), true
+	},
+	"wdir": func(d Data, opts options) (string, bool) {
+		w := d.Airport.METAR.Wind
+		switch {
+		case w.Calm:
+			return "", false
+		case w.Variable:
+			return "VRB", true
+		case opts["mag"] && w.DirectionMag == nil:
+			return "", false
+		case opts["mag"]:
+			return fmt.Sprintf("%03d", *w.DirectionMag), true
+		default:
+			return fmt.Sprintf("%03d", w.Direction), true
+		}
+	},
+	"wspd": func(d Data, _ options) (string, bool) {
+		return fmt.Sprintf("%d", d.Airport.METAR.Wind.Speed), true
+	},
+	"gust": func(d Data, _ options) (string, bool) {
+		if d.Airport.METAR.Wind.Gusts == nil {
+			return "", false
+		}
+		return fmt.Sprintf("%d", *d.Airport.METAR.Wind.Gusts), true
+	},
+	"best_rwy": func(d Data, _ options) (string, bool) {
+		rw, ok := bestRunway(d)
+		return rw.Runway, ok
+	},
+	"xwind": func(d Data, _ options) (string, bool) {
+		rw, ok := bestRunway(d)
+		return crosswind(rw), ok
+	},
+	"headwind": func(d Data, _ options) (string, bool) {
+		rw, ok := bestRunway(d)
+		return fmt.Sprintf("%d", rw.Headwind), ok
+	},
+	"runway_wind": func(d Data, _ options) (string, bool) {
+		rw, ok := bestRunway(d)
+		if !ok {
+			return "", false
+		}
+		return runwayWind(rw), true
+	},
+	"runways": func(d Data, opts options) (string, bool) {
+		ends := make([]string, 0, len(d.Airport.Runways))
+		for _, rw := range d.Airport.Runways {
+			ends = append(ends, runwayWind(rw))
+		}
+		return joinList(ends, opts), len(ends) > 0
+	},
+	"wx": func(d Data, opts options) (string, bool) {
+		texts := make([]string, 0, len(d.Airport.METAR.Weather))
+		for _, wx := range d.Airport.METAR.Weather {
+			texts = append(texts, wx.Text)
+		}
+		return joinList(texts, opts), len(texts) > 0
+	},
+	"wx_raw": func(d Data, _ options) (string, bool) {
+		return d.Airport.METAR.WxString, d.Airport.METAR.WxString != ""
+	},
+	"wx_icon": func(d Data, _ options) (string, bool) {
+		return weatherIcon(d.Airport.METAR.Weather)
+	},
+	"clouds": func(d Data, opts options) (string, bool) {
+		if d.Airport.METAR.Clouds == nil {
+			return "", false // sky not reported
+		}
+		if len(d.Airport.METAR.Clouds) == 0 {
+			return "clear", true
+		}
+		layers := make([]string, 0, len(d.Airport.METAR.Clouds))
+		for _, layer := range d.Airport.METAR.Clouds {
+			layers = append(layers, fmt.Sprintf("%s %d ft", layer.Coverage, layer.Base))
+		}
+		return joinList(layers, opts), true
+	},
+	"vis": func(d Data, opts options) (string, bool) {
+		vis := d.Airport.METAR.Visibility
+		if vis == nil {
+			return "", false
+		}
+		return vis.Format(opts["metric"]), true
+	},
+	"ceiling": func(d Data, _ options) (string, bool) {
+		for _, layer := range d.Airport.METAR.Clouds {
+			if layer.Coverage == "broken" || layer.Coverage == "overcast" {
+				return fmt.Sprintf("%d", layer.Base), true
+			}
+		}
+		return "", false
+	},
+	"temp": func(d Data, opts options) (string, bool) {
+		t := d.Airport.METAR.Temp
+		return formatTemp(t.Ambient, t.AmbientExact, opts), !t.NoTemp
+	},
+	"dewp": func(d Data, opts options) (string, bool) {
+		t := d.Airport.METAR.Temp
+		return formatTemp(t.Dewpoint, t.DewpointExact, opts), !t.NoDewpoint
+	},
+	"spread": func(d Data, opts options) (string, bool) {
+		t := d.Airport.METAR.Temp
+		if t.NoTemp || t.NoDewpoint {
+			return "", false
+		}
+		if opts["exact"] {
+			return fmt.Sprintf("%.1f", t.AmbientExact-t.DewpointExact), true
+		}
+		return fmt.Sprintf("%d", t.Ambient-t.Dewpoint), true
+	},
+	"altim": func(d Data, opts options) (string, bool) {
+		altim := d.Airport.METAR.Altimeter
+		if altim == 0 {
+			return "", false
+		}
+		if opts["hpa"] {
+			return fmt.Sprintf("%.0f", float64(altim)*33.8639), true
+		}
+		return fmt.Sprintf("%.2f", altim), true
+	},
+	"press_alt": func(d Data, _ options) (string, bool) {
+		return derived(d, func(v *types.Derived) int { return int(v.PressureAlt) })
+	},
+	"density_alt": func(d Data, _ options) (string, bool) {
+		return derivedFeet(d, func(v *types.Derived) *types.Feet { return v.DensityAlt })
+	},
+	"rh": func(d Data, _ options) (string, bool) {
+		v := d.Airport.Derived
+		if v == nil || v.RelHumidity == nil {
+			return "", false
+		}
+		return fmt.Sprintf("%.0f", *v.RelHumidity), true
+	},
+	"cu_base": func(d Data, _ options) (string, bool) {
+		v := d.Airport.Derived
+		if v == nil || v.CloudBase == nil {
+			return "", false
+		}
+		return fmt.Sprintf("%d", *v.CloudBase), true
+	},
+	"frz_level": func(d Data, _ options) (string, bool) {
+		return derivedFeet(d, func(v *types.Derived) *types.Feet { return v.FreezingLevel })
+	},
+	"taf_next": func(d Data, _ options) (string, bool) {
+		period := nextChange(d.Airport.TAF, d.Now)
+		if period == nil {
+			return "", false
+		}
+		return summarizePeriod(*period), true
+	},
+	"taf_raw": func(d Data, _ options) (string, bool) {
+		return d.Airport.TAF.Raw, d.Airport.TAF.Raw != ""
+	},
+	"taf_hourly": func(d Data, opts options) (string, bool) {
+		return hourlyCategories(d.Airport.TAF, d.Now, opts)
+	},
+	"taf_temps": func(d Data, opts options) (string, bool) {
+		return forecastTemps(d.Airport.TAF, opts)
+	},
+	"departure": func(d Data, _ options) (string, bool) {
+		dep := d.Airport.Departure
+		if dep == nil {
+			return "", false
+		}
+		at := time.Unix(dep.Time, 0).UTC().Format("1504Z")
+		if len(dep.Warnings) == 0 {
+			return fmt.Sprintf("%s %s", at, dep.Prevailing), true
+		}
+		return fmt.Sprintf("%s %s, %s", at, dep.Prevailing, strings.Join(dep.Warnings, "; ")), true
+	},
+	"minimums": func(d Data, opts options) (string, bool) {
+		check := d.Airport.Minimums
+		if check == nil || len(check.Violations) == 0 {
+			return "", false
+		}
+		return joinList(check.Violations, opts), true
+	},
+	"pireps": func(d Data, opts options) (string, bool) {
+		if opts["raw"] {
+			return listPIREPs(d.Airport.PIREPs)
+		}
+		return summarizePIREPs(d.Airport.PIREPs)
+	},
+	"advisories": func(d Data, opts options) (string, bool) {
+		return summarizeAdvisories(d.Airport.Advisories, d.Now, opts)
+	},
+	"afd_aviation": func(d Data, opts options) (string, bool) {
+		return afdSection(d.Airport.AFD, "AVIATION", opts)
+	},
+	"afd_new": func(d Data, _ options) (string, bool) {
+		if !AFDIsNew(d.Airport.AFD, d.Now) {
+			return "", false
+		}
+		issued := time.Unix(d.Airport.AFD.Issued, 0).UTC().Format("1504Z")
+		return fmt.Sprintf("New AFD issued %s", issued), true
+	},
+	"remarks": func(d Data, opts options) (string, bool) {
+		readable := d.Airport.METAR.Remarks.Readable
+		return joinList(readable, opts), len(readable) > 0
+	},
+}
+
+func derived(d Data, value func(*types.Derived) int) (string, bool) {
+	if d.Airport.Derived == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%d", value(d.Airport.Derived)), true
+}
+
+// derivedFeet is derived for the values that need a temperature
+func derivedFeet(d Data, value func(*types.Derived) *types.Feet) (string, bool) {
+	if d.Airport.Derived == nil || value(d.Airport.Derived) == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%d", *value(d.Airport.Derived)), true
+}
+
+func joinList(items []string, opts options) string {
+	if opts["lines"] {
+		return strings.Join(items, "\n")
+	}
+	return strings.Join(items, ", ")
+}
+
+func formatTemp(whole int, exact float64, opts options) string {
+	switch {
+	case opts["f"] && opts["exact"]:
+		return fmt.Sprintf("%.1f", exact*9/5+32)
+	case opts["f"]:
+		return fmt.Sprintf("%.0f", exact*9/5+32)
+	case opts["exact"]:
+		return fmt.Sprintf("%.1f", exact)
+	default:
+		return fmt.Sprintf("%d", whole)
+	}
+}
+
+func formatWind(w types.WindData, raw bool) string {
+	varying := ""
+	if raw {
+		dir := fmt.Sprintf("%03d", w.Direction)
+		if w.Variable {
+			dir = "VRB"
+		}
+		if w.Varying != nil {
+			varying = fmt.Sprintf(" %03dV%03d", w.Varying.From, w.Varying.To)
+		}
+		if w.Gusts != nil {
+			return fmt.Sprintf("%s%02dG%02dKT%s", dir, w.Speed, *w.Gusts, varying)
+		}
+		return fmt.Sprintf("%s%02dKT%s", dir, w.Speed, varying)
+	}
+
+	if w.Varying != nil {
+		varying = fmt.Sprintf(", varying %03d°-%03d°", w.Varying.From, w.Varying.To)
+	}
+	switch {
+	case w.Calm:
+		return "calm"
+	case w.Variable && w.Gusts != nil:
+		return fmt.Sprintf("VRB @ %dG%dkt", w.Speed, *w.Gusts)
+	case w.Variable:
+		return fmt.Sprintf("VRB @ %dkt", w.Speed)
+	case w.Gusts != nil:
+		return fmt.Sprintf("%03d° @ %dG%dkt%s", w.Direction, w.Speed, *w.Gusts, varying)
+	default:
+		return fmt.Sprintf("%03d° @ %dkt%s", w.Direction, w.Speed, varying)
+	}
+}
+
+// formatWindMag is formatWind's plain form with the magnetic direction
+func formatWindMag(w types.WindData) string {
+	if w.Gusts != nil {
+		return fmt.Sprintf("%03d°M @ %dG%dkt", *w.DirectionMag, w.Speed, *w.Gusts)
+	}
+	return fmt.Sprintf("%03d°M @ %dkt", *w.DirectionMag, w.Speed)
+}
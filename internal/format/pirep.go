@@ -0,0 +1,98 @@
+package format
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/house-holder/pilot-bar/internal/parse"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+type hazardCount struct {
+	intensity string
+	kind      string // TURB or ICE
+	count     int
+}
+
+// summarizePIREPs counts reports by their strongest turbulence and icing,
+// e.g. "2 MOD TURB, 1 LGT ICE within 50nm"
+func summarizePIREPs(area types.PIREPArea) (string, bool) {
+	if area.Fetched == 0 {
+		return "", false
+	}
+	within := fmt.Sprintf("within %.0fnm", area.RadiusNM)
+	if len(area.Reports) == 0 {
+		return "none " + within, true
+	}
+
+	var counts []hazardCount
+	add := func(intensity, kind string) {
+		for i := range counts {
+			if counts[i].intensity == intensity && counts[i].kind == kind {
+				counts[i].count++
+				return
+			}
+		}
+		counts = append(counts, hazardCount{intensity: intensity, kind: kind, count: 1})
+	}
+
+	urgent := 0
+	for _, report := range area.Reports {
+		if report.Urgent {
+			urgent++
+		}
+		if peak, ok := peakLayer(report.Turbulence); ok {
+			add(peak, "TURB")
+		}
+		if peak, ok := peakLayer(report.Icing); ok {
+			add(peak, "ICE")
+		}
+	}
+
+	slices.SortFunc(counts, func(a, b hazardCount) int {
+		if c := cmp.Compare(parse.PeakIntensityRank(b.intensity), parse.PeakIntensityRank(a.intensity)); c != 0 {
+			return c
+		}
+		return strings.Compare(b.kind, a.kind) // TURB before ICE
+	})
+
+	parts := make([]string, 0, len(counts)+1)
+	if urgent > 0 {
+		parts = append(parts, fmt.Sprintf("%d UUA", urgent))
+	}
+	for _, c := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s %s", c.count, c.intensity, c.kind))
+	}
+	if len(counts) == 0 {
+		parts = append(parts, fmt.Sprintf("%d reports, no TURB/ICE", len(area.Reports)))
+	}
+	return strings.Join(parts, ", ") + " " + within, true
+}
+
+func peakLayer(layers []types.PIREPLayer) (string, bool) {
+	peak := ""
+	for _, layer := range layers {
+		intensity, ok := parse.PeakIntensity(layer.Intensity)
+		if ok && parse.PeakIntensityRank(intensity) > parse.PeakIntensityRank(peak) {
+			peak = intensity
+		}
+	}
+	return peak, peak != ""
+}
+
+// listPIREPs gives one line per report, nearest first:
+// "12nm 240° 8000ft ANC UA /OV ..."
+func listPIREPs(area types.PIREPArea) (string, bool) {
+	lines := make([]string, 0, len(area.Reports))
+	for _, report := range area.Reports {
+		alt := "---"
+		if report.Altitude != nil {
+			alt = fmt.Sprintf("%dft", *report.Altitude)
+		}
+		lines = append(lines, fmt.Sprintf("%.0fnm %03.0f° %s %s",
+			report.DistanceNM, report.Bearing, alt, report.Raw))
+	}
+	return strings.Join(lines, "\n"), len(lines) > 0
+}
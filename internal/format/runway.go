@@ -0,0 +1,35 @@
+package format
+
+import (
+	"fmt"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// runwayWind describes one runway end, e.g. "14: 5kt head, 8G12kt cross from R"
+func runwayWind(rw types.RunwayWind) string {
+	along := fmt.Sprintf("%dkt head", rw.Headwind)
+	if rw.Headwind < 0 {
+		along = fmt.Sprintf("%dkt tail", -rw.Headwind)
+	}
+	cross := crosswind(rw) + "kt cross"
+	if rw.CrossFrom != "" {
+		cross += " from " + rw.CrossFrom
+	}
+	return fmt.Sprintf("%s: %s, %s", rw.Runway, along, cross)
+}
+
+// crosswind is the steady component, with the gust component when gusting
+func crosswind(rw types.RunwayWind) string {
+	if rw.GustCrosswind != nil && *rw.GustCrosswind > rw.Crosswind {
+		return fmt.Sprintf("%dG%d", rw.Crosswind, *rw.GustCrosswind)
+	}
+	return fmt.Sprintf("%d", rw.Crosswind)
+}
+
+func bestRunway(d Data) (types.RunwayWind, bool) {
+	if len(d.Airport.Runways) == 0 {
+		return types.RunwayWind{}, false
+	}
+	return d.Airport.Runways[0], true
+}
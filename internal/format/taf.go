@@ -0,0 +1,121 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/forecast"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+const defaultHourlySpan = 12
+
+// nextChange finds the first change group starting after now
+func nextChange(taf types.TAF, now time.Time) *types.TAFPeriod {
+	for i, period := range taf.Periods {
+		if period.Change != "BASE" && period.From > now.Unix() {
+			return &taf.Periods[i]
+		}
+	}
+	return nil
+}
+
+// summarizePeriod renders a change group compactly, e.g.
+// "FM 1800Z 200° @ 17G26kt 6+SM broken 24000 ft"
+func summarizePeriod(p types.TAFPeriod) string {
+	from := time.Unix(p.From, 0).UTC().Format("1504Z")
+	to := time.Unix(p.To, 0).UTC().Format("1504Z")
+
+	var parts []string
+	switch p.Change {
+	case "FM":
+		parts = append(parts, "FM "+from)
+	case "PROB":
+		change := fmt.Sprintf("PROB%d", p.Probability)
+		if p.Temporary {
+			change += " TEMPO"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s-%s", change, from, to))
+	default:
+		parts = append(parts, fmt.Sprintf("%s %s-%s", p.Change, from, to))
+	}
+
+	if p.Wind != nil {
+		parts = append(parts, formatWind(*p.Wind, false))
+	}
+	if p.Visibility != nil {
+		parts = append(parts, p.Visibility.Format(false))
+	}
+	if p.WxString != "" {
+		parts = append(parts, p.WxString)
+	}
+	if p.Clouds != nil && len(p.Clouds) == 0 {
+		parts = append(parts, "clear")
+	}
+	for _, layer := range p.Clouds {
+		parts = append(parts, fmt.Sprintf("%s %d ft", layer.Coverage, layer.Base))
+	}
+	if p.WindShear != nil {
+		parts = append(parts, fmt.Sprintf("WS %d ft %03d° @ %dkt",
+			p.WindShear.Height, p.WindShear.Direction, p.WindShear.Speed))
+	}
+	return strings.Join(parts, " ")
+}
+
+// forecastTemps lists the TAF's TX/TN groups, e.g. "max 24 2100Z, min 12 1100Z"
+func forecastTemps(taf types.TAF, opts options) (string, bool) {
+	var parts []string
+	for _, period := range taf.Periods {
+		for _, temp := range period.Temps {
+			at := time.Unix(temp.At, 0).UTC().Format("1504Z")
+			value := temp.Celsius
+			if opts["f"] {
+				value = value*9/5 + 32
+			}
+			parts = append(parts, fmt.Sprintf("%s %.0f %s", strings.ToLower(temp.Kind), value, at))
+		}
+	}
+	return strings.Join(parts, ", "), len(parts) > 0
+}
+
+// hourlyCategories lists the forecast category per hour, with the worst
+// case alongside when a temporary group makes it lower, e.g.
+// "1500Z  MVFR (TEMPO IFR)". A numeric option sets the span in hours;
+// compact gives "15 MVFR/IFR  16 VFR ..." on one line.
+func hourlyCategories(taf types.TAF, now time.Time, opts options) (string, bool) {
+	hours := defaultHourlySpan
+	for opt := range opts {
+		if n, err := strconv.Atoi(opt); err == nil && n > 0 {
+			hours = n
+		}
+	}
+
+	resolved := forecast.Hourly(taf, now, hours)
+	if len(resolved) == 0 {
+		return "", false
+	}
+
+	entries := make([]string, 0, len(resolved))
+	for _, r := range resolved {
+		at := time.Unix(r.Time, 0).UTC()
+		worse := r.WorstFrom != nil
+		switch {
+		case opts["compact"] && worse:
+			entries = append(entries, fmt.Sprintf("%s %s/%s", at.Format("15"), r.Prevailing.FltCat, r.Worst.FltCat))
+		case opts["compact"]:
+			entries = append(entries, fmt.Sprintf("%s %s", at.Format("15"), r.Prevailing.FltCat))
+		case worse:
+			entries = append(entries, fmt.Sprintf("%s  %-4s (%s %s)", at.Format("1504Z"),
+				r.Prevailing.FltCat, r.WorstFrom.Change, r.Worst.FltCat))
+		default:
+			entries = append(entries, fmt.Sprintf("%s  %s", at.Format("1504Z"), r.Prevailing.FltCat))
+		}
+	}
+
+	if opts["compact"] {
+		return strings.Join(entries, "  "), true
+	}
+	return strings.Join(entries, "\n"), true
+}
@@ -0,0 +1,184 @@
+// 'format' renders user-defined bar text and tooltip templates.
+//
+// Syntax:
+//
+//	{field}          placeholder, see fields.go for names
+//	{field:a,b}      placeholder with comma-separated format options
+//	[ ... ]          conditional segment, dropped if any field inside is empty
+//	\{ \} \[ \] \\   literal characters
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	nodeText nodeKind = iota
+	nodeField
+	nodeSegment
+)
+
+type node struct {
+	kind     nodeKind
+	text     string
+	field    string
+	options  options
+	children []node
+}
+
+type Template struct {
+	source string
+	nodes  []node
+}
+
+// Parse compiles a template, rejecting unknown fields and unbalanced brackets
+func Parse(src string) (*Template, error) {
+	p := &parser{src: src}
+	nodes, err := p.parseNodes(0)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{source: src, nodes: nodes}, nil
+}
+
+func (t *Template) String() string {
+	return t.source
+}
+
+// Render fills the template from data. Empty fields at the top level render
+// as nothing; inside a segment they remove the whole segment.
+func (t *Template) Render(data Data) string {
+	var b strings.Builder
+	renderNodes(&b, t.nodes, data)
+	return b.String()
+}
+
+func renderNodes(b *strings.Builder, nodes []node, data Data) bool {
+	complete := true
+	for _, n := range nodes {
+		switch n.kind {
+		case nodeText:
+			b.WriteString(n.text)
+		case nodeField:
+			value, ok := fields[n.field](data, n.options)
+			if !ok || value == "" {
+				complete = false
+				continue
+			}
+			b.WriteString(n.options.apply(value))
+		case nodeSegment:
+			var seg strings.Builder
+			if renderNodes(&seg, n.children, data) {
+				b.WriteString(seg.String())
+			}
+		}
+	}
+	return complete
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) parseNodes(depth int) ([]node, error) {
+	var nodes []node
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, node{kind: nodeText, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		switch ch {
+		case '\\':
+			if p.pos+1 >= len(p.src) {
+				return nil, fmt.Errorf("template: trailing escape at %d", p.pos)
+			}
+			text.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case '{':
+			flush()
+			n, err := p.parseField()
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, n)
+		case '}':
+			return nil, fmt.Errorf("template: unexpected '}' at %d", p.pos)
+		case '[':
+			flush()
+			start := p.pos
+			p.pos++
+			children, err := p.parseNodes(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+				return nil, fmt.Errorf("template: unclosed '[' at %d", start)
+			}
+			p.pos++
+			nodes = append(nodes, node{kind: nodeSegment, children: children})
+		case ']':
+			if depth == 0 {
+				return nil, fmt.Errorf("template: unexpected ']' at %d", p.pos)
+			}
+			flush()
+			return nodes, nil
+		default:
+			text.WriteByte(ch)
+			p.pos++
+		}
+	}
+
+	flush()
+	return nodes, nil
+}
+
+func (p *parser) parseField() (node, error) {
+	start := p.pos
+	end := strings.IndexByte(p.src[start:], '}')
+	if end == -1 {
+		return node{}, fmt.Errorf("template: unclosed '{' at %d", start)
+	}
+	body := p.src[start+1 : start+end]
+	p.pos = start + end + 1
+
+	name, opts, _ := strings.Cut(body, ":")
+	name = strings.TrimSpace(name)
+	if _, ok := fields[name]; !ok {
+		return node{}, fmt.Errorf("template: unknown field {%s}", name)
+	}
+	return node{kind: nodeField, field: name, options: parseOptions(opts)}, nil
+}
+
+type options map[string]bool
+
+func parseOptions(raw string) options {
+	opts := options{}
+	for opt := range strings.SplitSeq(raw, ",") {
+		if opt = strings.TrimSpace(opt); opt != "" {
+			opts[opt] = true
+		}
+	}
+	return opts
+}
+
+// apply handles the options valid for every field
+func (o options) apply(value string) string {
+	switch {
+	case o["upper"]:
+		return strings.ToUpper(value)
+	case o["lower"]:
+		return strings.ToLower(value)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,140 @@
+package format
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/parse"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// testdataMETARs decodes testdata/metar.json, keyed by station and
+// observation, e.g. "KSGF 251752Z", each seen 42 minutes after it was taken
+func testdataMETARs(t *testing.T) map[string]Data {
+	t.Helper()
+	raw, err := os.ReadFile("../../testdata/metar.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []types.METARresponse
+	if err := json.Unmarshal(raw, &records); err != nil {
+		t.Fatal(err)
+	}
+
+	out := map[string]Data{}
+	for _, record := range records {
+		var metar types.METAR
+		if err := parse.BuildInternalMETAR(&record, &metar); err != nil {
+			t.Fatalf("%s: %v", record.IcaoID, err)
+		}
+		metar.Reported.Epoch = record.ObsTime
+		obs := time.Unix(record.ObsTime, 0)
+		key := record.IcaoID + " " + obs.UTC().Format("021504Z")
+		out[key] = Data{
+			Airport: types.Airport{ICAO: record.IcaoID, Name: record.Name, METAR: metar},
+			Now:     obs.Add(42 * time.Minute),
+		}
+	}
+	return out
+}
+
+func render(t *testing.T, src string, data Data) string {
+	t.Helper()
+	tmpl, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", src, err)
+	}
+	return tmpl.Render(data)
+}
+
+func TestRenderTestdata(t *testing.T) {
+	metars := testdataMETARs(t)
+	tests := []struct {
+		metar    string
+		template string
+		want     string
+	}{
+		{"KSGF 251752Z", "{icao} {fltcat} [{wdir}@{wspd}[G{gust}]kt ]{altim}", "KSGF VFR 120@8kt 30.10"},
+		{"KLBL 251756Z", "{icao} {fltcat} [{wdir}@{wspd}[G{gust}]kt ]{altim}", "KLBL IFR 320@9kt 30.04"},
+		{"PAMH 251756Z", "{icao} {fltcat} [{wdir}@{wspd}[G{gust}]kt ]{altim}", "PAMH MVFR 29.62"},
+		{"KAMA 251753Z", "{icao} {fltcat} [{wdir}@{wspd}[G{gust}]kt ]{altim}", "KAMA VFR 210@22G31kt 29.81"},
+		{"PAMH 251756Z", "{wind}|{wind:raw}", "calm|00000KT"},
+		{"KSPS 251752Z", "{wind}|{wind:raw}", "010° @ 8kt|01008KT"},
+		{"PAMH 251756Z", "{vis} {vis:metric}", "4SM 6km"},
+		{"KSGF 251652Z", "{temp}/{dewp} {temp:f} {temp:exact} {dewp:exact}", "12/9 54 12.2 8.9"},
+		{"PAMH 251756Z", "{temp}/{dewp} {temp:exact}", "-6/-7 -5.6"},
+		{"KSGF 251652Z", "{altim} {altim:hpa}", "30.12 1020"},
+		{"KSPS 251752Z", "{icao:lower} {fltcat:lower} {name:upper}", "ksps mvfr WICHITA FALLS RGNL, TX, US"},
+		{"KLBL 251756Z", "{age} {age:min} {obs}", "42m 42 251756Z"},
+		{"KLBL 251756Z", "{clouds}|{ceiling}", "broken 800 ft, overcast 1400 ft|800"},
+		{"KLBL 251756Z", "{clouds:lines}", "broken 800 ft\novercast 1400 ft"},
+		{"KSGF 251752Z", "[Weather: {wx}]", "Weather: light rain"},
+		{"KSGF 251652Z", "[Weather: {wx}]", ""},
+		{"PAMH 251756Z", "{wx:lines}", "light snow\nmist"},
+		{"KSGF 251652Z", "{remarks:lines}", "automated station with precipitation discriminator\n" +
+			"rain ended 1637Z\nsea-level pressure 1019.9 hPa\n1-hour precipitation 0.01 in\n" +
+			"temperature 12.2°C, dewpoint 8.9°C"},
+		{"KSGF 251652Z", `\{{icao}\} \[x\] \\`, `{KSGF} [x] \`},
+		{"KSGF 251652Z", "{icao:nonsense}", "KSGF"},
+	}
+	for _, tt := range tests {
+		data, ok := metars[tt.metar]
+		if !ok {
+			t.Fatalf("no METAR %s in testdata", tt.metar)
+		}
+		if got := render(t, tt.template, data); got != tt.want {
+			t.Errorf("%s %q:\n got %q\nwant %q", tt.metar, tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestGustSegment(t *testing.T) {
+	data := testdataMETARs(t)["KSGF 251752Z"]
+	const src = "{wdir}@{wspd}[G{gust}]kt"
+
+	if got := render(t, src, data); got != "120@8kt" {
+		t.Errorf("without gusts: got %q", got)
+	}
+	gust := types.Knots(18)
+	data.Airport.METAR.Wind.Gusts = &gust
+	if got := render(t, src, data); got != "120@8G18kt" {
+		t.Errorf("with gusts: got %q", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{icao} {nope}", "unknown field {nope}"},
+		{"{icao", "unclosed '{'"},
+		{"[{gust}", "unclosed '['"},
+		{"{icao}]", "unexpected ']'"},
+		{"icao}", "unexpected '}'"},
+		{`{icao}\`, "trailing escape"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.template)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) = %v, want error containing %q", tt.template, err, tt.want)
+		}
+	}
+}
+
+func TestDefaultsRender(t *testing.T) {
+	data := testdataMETARs(t)["KLBL 251756Z"]
+	templates := Defaults()
+	text, tooltip := templates.Text.Render(data), templates.Tooltip.Render(data)
+	if text != "KLBL IFR 42m" {
+		t.Errorf("text = %q", text)
+	}
+	for _, want := range []string{"Observed:  251756Z (42m ago)", "Wind:      320° @ 9kt", data.Airport.METAR.Raw} {
+		if !strings.Contains(tooltip, want) {
+			t.Errorf("tooltip missing %q:\n%s", want, tooltip)
+		}
+	}
+}
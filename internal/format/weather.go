@@ -0,0 +1,39 @@
+package format
+
+import "github.com/house-holder/pilot-bar/pkg/types"
+
+// weatherIcon picks one glyph for the most significant present weather
+func weatherIcon(weather []types.Weather) (string, bool) {
+	var has struct{ ts, severe, fzra, snow, rain, obsc bool }
+	for _, wx := range weather {
+		has.ts = has.ts || wx.Thunderstorm
+		has.severe = has.severe || wx.Severe
+		has.fzra = has.fzra || wx.FreezingPrecip
+		has.obsc = has.obsc || wx.Obscuration
+		for _, code := range wx.Phenomena {
+			switch code {
+			case "SN", "SG", "IC", "PL":
+				has.snow = true
+			case "RA", "DZ", "GR", "GS", "UP":
+				has.rain = true
+			}
+		}
+	}
+
+	switch {
+	case has.ts:
+		return "⛈", true
+	case has.severe:
+		return "🌪", true
+	case has.fzra:
+		return "🧊", true
+	case has.snow:
+		return "🌨", true
+	case has.rain:
+		return "🌧", true
+	case has.obsc:
+		return "🌫", true
+	default:
+		return "", false
+	}
+}
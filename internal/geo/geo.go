@@ -0,0 +1,107 @@
+// 'geo' holds the spherical-earth helpers used to relate reports and
+// advisories to the selected airport.
+package geo
+
+import "math"
+
+const EarthRadiusNM = 3440.065
+
+func rad(deg float64) float64 { return deg * math.Pi / 180 }
+func deg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// DistanceNM is the great-circle distance between two points
+func DistanceNM(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := rad(lat2 - lat1)
+	dLon := rad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusNM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Bearing is the initial true course from the first point to the second,
+// 0-360
+func Bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	y := math.Sin(rad(lon2-lon1)) * math.Cos(rad(lat2))
+	x := math.Cos(rad(lat1))*math.Sin(rad(lat2)) -
+		math.Sin(rad(lat1))*math.Cos(rad(lat2))*math.Cos(rad(lon2-lon1))
+	return math.Mod(deg(math.Atan2(y, x))+360, 360)
+}
+
+// Offset is the point distNM from the origin along a true course
+func Offset(lat, lon, bearing, distNM float64) (float64, float64) {
+	d := distNM / EarthRadiusNM
+	b := rad(bearing)
+	lat1, lon1 := rad(lat), rad(lon)
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1),
+		math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return deg(lat2), math.Mod(deg(lon2)+540, 360) - 180
+}
+
+// BBox is a lat/lon box that contains every point within radiusNM
+func BBox(lat, lon, radiusNM float64) (minLat, minLon, maxLat, maxLon float64) {
+	dLat := radiusNM / 60
+	cos := math.Cos(rad(lat))
+	dLon := 180.0
+	if cos > 1e-6 {
+		dLon = math.Min(180, radiusNM/(60*cos))
+	}
+	return math.Max(-90, lat-dLat), math.Max(-180, lon-dLon),
+		math.Min(90, lat+dLat), math.Min(180, lon+dLon)
+}
+
+// Point is a latitude/longitude in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// InPolygon reports whether the point lies inside the polygon, which may
+// be given open or closed. Edges are treated as straight in lat/lon, as
+// advisory areas are drawn.
+func InPolygon(lat, lon float64, polygon []Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > lat) != (b.Lat > lat) &&
+			lon < (b.Lon-a.Lon)*(lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// DistanceToPolygonNM is 0 inside the polygon, otherwise the distance to
+// its nearest edge. Edges are measured on a flat projection around the
+// point, which is close enough within a few hundred miles.
+func DistanceToPolygonNM(lat, lon float64, polygon []Point) float64 {
+	if len(polygon) == 0 {
+		return math.Inf(1)
+	}
+	if InPolygon(lat, lon, polygon) {
+		return 0
+	}
+
+	cos := math.Cos(rad(lat))
+	project := func(p Point) (float64, float64) {
+		return (p.Lon - lon) * 60 * cos, (p.Lat - lat) * 60
+	}
+
+	nearest := math.Inf(1)
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		ax, ay := project(polygon[j])
+		bx, by := project(polygon[i])
+		nearest = math.Min(nearest, originToSegment(ax, ay, bx, by))
+	}
+	return nearest
+}
+
+func originToSegment(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSq))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
@@ -0,0 +1,63 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+// square is a 2° box around KSGF, closed the way advisories send it
+var square = []Point{
+	{36, -94}, {36, -92}, {38, -92}, {38, -94}, {36, -94},
+}
+
+func TestInPolygon(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		polygon  []Point
+		want     bool
+	}{
+		{"inside", 37.2, -93.4, square, true},
+		{"outside east", 37.2, -91.9, square, false},
+		{"outside north", 38.1, -93, square, false},
+		{"open polygon", 37.2, -93.4, square[:4], true},
+		{"concave notch", 37.5, -93,
+			[]Point{{36, -94}, {36, -92}, {38, -92}, {37, -93}, {38, -94}}, false},
+		{"empty", 37.2, -93.4, nil, false},
+	}
+	for _, tt := range tests {
+		if got := InPolygon(tt.lat, tt.lon, tt.polygon); got != tt.want {
+			t.Errorf("%s: InPolygon = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDistanceToPolygonNM(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     float64
+	}{
+		{"inside", 37.2, -93.4, 0},
+		{"on west edge", 37, -94, 0},
+		{"on east edge", 37, -92, 0},
+		{"on north edge", 38, -93, 0},
+		{"corner", 36, -94, 0},
+		// one degree of latitude, R·π/180
+		{"north of edge", 39, -93, 60.04},
+		// cross-track to the -94 meridian, R·asin(sin 1°·cos 37°)
+		{"west of edge", 37, -95, 47.95},
+		// haversine to the (38, -92) corner
+		{"off corner", 38.5, -91.5, 38.17},
+	}
+	for _, tt := range tests {
+		got := DistanceToPolygonNM(tt.lat, tt.lon, square)
+		if math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("%s: DistanceToPolygonNM = %.2f, want %.2f", tt.name, got, tt.want)
+		}
+	}
+
+	if got := DistanceToPolygonNM(37, -93, nil); !math.IsInf(got, 1) {
+		t.Errorf("empty polygon = %v, want +Inf", got)
+	}
+}
@@ -0,0 +1,35 @@
+package geo
+
+import (
+	"math"
+	"time"
+)
+
+// sunriseElevation is the sun's altitude at sunrise/sunset, allowing for
+// refraction and the solar disc
+const sunriseElevation = -0.833
+
+// SolarElevation is the sun's altitude above the horizon in degrees, from
+// the low-precision almanac formulas (good to about a hundredth of a degree)
+func SolarElevation(lat, lon float64, t time.Time) float64 {
+	d := float64(t.Unix())/86400 - 10957.5 // days since J2000.0
+
+	g := rad(357.529 + 0.98560028*d)
+	q := 280.459 + 0.98564736*d
+	eclipticLon := rad(q + 1.915*math.Sin(g) + 0.020*math.Sin(2*g))
+	obliquity := rad(23.439 - 0.00000036*d)
+
+	declination := math.Asin(math.Sin(obliquity) * math.Sin(eclipticLon))
+	rightAscension := math.Atan2(math.Cos(obliquity)*math.Sin(eclipticLon), math.Cos(eclipticLon))
+
+	siderealHours := 18.697374558 + 24.06570982441908*d
+	hourAngle := rad(math.Mod(siderealHours*15+lon, 360)) - rightAscension
+
+	return deg(math.Asin(math.Sin(rad(lat))*math.Sin(declination) +
+		math.Cos(rad(lat))*math.Cos(declination)*math.Cos(hourAngle)))
+}
+
+// IsDaylight reports whether t falls between sunrise and sunset at the point
+func IsDaylight(lat, lon float64, t time.Time) bool {
+	return SolarElevation(lat, lon, t) > sunriseElevation
+}
@@ -0,0 +1,269 @@
+package geo
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WMM.COF is the NOAA/BGS World Magnetic Model coefficient file, used as
+// published. A newer epoch's file drops in unchanged, here or at run time
+// through UseModel.
+//
+//go:embed WMM.COF
+var wmmCOF []byte
+
+const (
+	wgs84A       = 6378.137 // km
+	wgs84F       = 1 / 298.257223563
+	wmmRefRadius = 6371.2 // km, geomagnetic reference sphere
+)
+
+// wmmModel holds the Gauss coefficients g, h and their yearly change,
+// indexed [n][m]
+type wmmModel struct {
+	name   string
+	epoch  float64
+	degree int
+	g, h   [][]float64
+	dg, dh [][]float64
+}
+
+var (
+	wmmOnce sync.Once
+	wmm     *wmmModel
+	wmmErr  error
+)
+
+// UseModel replaces the embedded model with the coefficient file at path,
+// e.g. a newer NOAA release than the one built in. Call it before the
+// first Declination.
+func UseModel(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	model, err := parseCOF(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	wmmOnce.Do(func() {}) // the embedded file is no longer needed
+	wmm, wmmErr = model, nil
+	return nil
+}
+
+func loadWMM() (*wmmModel, error) {
+	wmmOnce.Do(func() {
+		wmm, wmmErr = parseCOF(wmmCOF)
+	})
+	return wmm, wmmErr
+}
+
+func parseCOF(data []byte) (*wmmModel, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("WMM.COF: empty")
+	}
+	header := strings.Fields(scanner.Text())
+	if len(header) < 2 {
+		return nil, fmt.Errorf("WMM.COF: header %q", scanner.Text())
+	}
+	epoch, err := strconv.ParseFloat(header[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("WMM.COF: epoch %q", header[0])
+	}
+
+	type row struct {
+		n, m         int
+		g, h, dg, dh float64
+	}
+	var rows []row
+	degree := 0
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 1 && strings.HasPrefix(fields[0], "9999") {
+			break
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("WMM.COF: line %q", scanner.Text())
+		}
+		var r row
+		var errs [6]error
+		r.n, errs[0] = strconv.Atoi(fields[0])
+		r.m, errs[1] = strconv.Atoi(fields[1])
+		r.g, errs[2] = strconv.ParseFloat(fields[2], 64)
+		r.h, errs[3] = strconv.ParseFloat(fields[3], 64)
+		r.dg, errs[4] = strconv.ParseFloat(fields[4], 64)
+		r.dh, errs[5] = strconv.ParseFloat(fields[5], 64)
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("WMM.COF: line %q: %w", scanner.Text(), err)
+			}
+		}
+		if r.m > r.n || r.n < 1 {
+			return nil, fmt.Errorf("WMM.COF: line %q: bad degree/order", scanner.Text())
+		}
+		degree = max(degree, r.n)
+		rows = append(rows, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("WMM.COF: %w", err)
+	}
+
+	model := &wmmModel{name: header[1], epoch: epoch, degree: degree}
+	for _, c := range []*[][]float64{&model.g, &model.h, &model.dg, &model.dh} {
+		*c = make([][]float64, degree+1)
+		for n := range *c {
+			(*c)[n] = make([]float64, n+1)
+		}
+	}
+	for _, r := range rows {
+		model.g[r.n][r.m], model.h[r.n][r.m] = r.g, r.h
+		model.dg[r.n][r.m], model.dh[r.n][r.m] = r.dg, r.dh
+	}
+	return model, nil
+}
+
+// MagneticModel names the model in use and the years it is valid for;
+// outside them the secular variation is extrapolated and error grows
+func MagneticModel() (name string, from, to float64, err error) {
+	model, err := loadWMM()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return model.name, model.epoch, model.epoch + 5, nil
+}
+
+// Declination is the magnetic variation in degrees, east positive, at a
+// point altitudeKm above the WGS84 ellipsoid
+func Declination(lat, lon, altitudeKm float64, t time.Time) (float64, error) {
+	model, err := loadWMM()
+	if err != nil {
+		return 0, err
+	}
+	x, y, _ := model.field(lat, lon, altitudeKm, decimalYear(t))
+	return deg(math.Atan2(y, x)), nil
+}
+
+// field is the north, east and down components in nT
+func (model *wmmModel) field(lat, lon, altitudeKm, year float64) (x, y, z float64) {
+	// geodetic to geocentric spherical
+	e2 := wgs84F * (2 - wgs84F)
+	sinLat, cosLat := math.Sincos(rad(lat))
+	rc := wgs84A / math.Sqrt(1-e2*sinLat*sinLat)
+	p := (rc + altitudeKm) * cosLat
+	zc := (rc*(1-e2) + altitudeKm) * sinLat
+	r := math.Hypot(p, zc)
+	latC := math.Asin(zc / r)
+
+	// colatitude for the Legendre recursion; keep off the exact pole
+	theta := math.Pi/2 - latC
+	sinT, cosT := math.Sincos(theta)
+	if math.Abs(sinT) < 1e-10 {
+		sinT = 1e-10
+	}
+
+	P, dP := schmidtLegendre(model.degree, sinT, cosT)
+	dt := year - model.epoch
+	lambda := rad(lon)
+
+	var bTheta, bPhi, bR float64
+	ratio := wmmRefRadius / r
+	scale := ratio * ratio
+	for n := 1; n <= model.degree; n++ {
+		scale *= ratio // (a/r)^(n+2)
+		for m := 0; m <= n; m++ {
+			g := model.g[n][m] + dt*model.dg[n][m]
+			h := model.h[n][m] + dt*model.dh[n][m]
+			sinM, cosM := math.Sincos(float64(m) * lambda)
+			bR += float64(n+1) * scale * (g*cosM + h*sinM) * P[n][m]
+			bTheta -= scale * (g*cosM + h*sinM) * dP[n][m]
+			bPhi += scale * float64(m) * (g*sinM - h*cosM) * P[n][m] / sinT
+		}
+	}
+
+	// spherical north/east/down, then rotated onto the geodetic vertical
+	xs, ys, zs := -bTheta, bPhi, -bR
+	psi := latC - rad(lat)
+	sinP, cosP := math.Sincos(psi)
+	return xs*cosP - zs*sinP, ys, xs*sinP + zs*cosP
+}
+
+// schmidtLegendre is the Schmidt semi-normalised associated Legendre
+// function P[n][m](cos θ) and its derivative in θ
+func schmidtLegendre(degree int, sinT, cosT float64) (P, dP [][]float64) {
+	P = make([][]float64, degree+1)
+	dP = make([][]float64, degree+1)
+	for n := range P {
+		P[n] = make([]float64, n+1)
+		dP[n] = make([]float64, n+1)
+	}
+
+	// Gauss-normalised recursion
+	P[0][0] = 1
+	for n := 1; n <= degree; n++ {
+		for m := 0; m <= n; m++ {
+			switch {
+			case m == n:
+				P[n][m] = sinT * P[n-1][m-1]
+				dP[n][m] = sinT*dP[n-1][m-1] + cosT*P[n-1][m-1]
+			case n == 1:
+				P[n][m] = cosT * P[n-1][m]
+				dP[n][m] = cosT*dP[n-1][m] - sinT*P[n-1][m]
+			default:
+				P[n][m] = cosT * P[n-1][m]
+				dP[n][m] = cosT*dP[n-1][m] - sinT*P[n-1][m]
+				if m <= n-2 {
+					k := float64((n-1)*(n-1)-m*m) / float64((2*n-1)*(2*n-3))
+					P[n][m] -= k * P[n-2][m]
+					dP[n][m] -= k * dP[n-2][m]
+				}
+			}
+		}
+	}
+
+	// Schmidt semi-normalisation
+	s := 1.0
+	for n := 1; n <= degree; n++ {
+		s *= float64(2*n-1) / float64(n)
+		sm := s
+		for m := 0; m <= n; m++ {
+			if m > 0 {
+				factor := 1.0
+				if m == 1 {
+					factor = 2
+				}
+				sm *= math.Sqrt(float64(n-m+1) * factor / float64(n+m))
+			}
+			P[n][m] *= sm
+			dP[n][m] *= sm
+		}
+	}
+	return P, dP
+}
+
+// decimalYear is t as a fractional year, e.g. 2025.5 for early July
+func decimalYear(t time.Time) float64 {
+	t = t.UTC()
+	start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	return float64(t.Year()) + t.Sub(start).Seconds()/end.Sub(start).Seconds()
+}
+
+// TrueToMagnetic converts a true bearing to magnetic, 0-360, given the
+// declination (east positive)
+func TrueToMagnetic(bearing, declination float64) float64 {
+	return math.Mod(math.Mod(bearing-declination, 360)+360, 360)
+}
+
+// MagneticToTrue is the inverse of TrueToMagnetic
+func MagneticToTrue(bearing, declination float64) float64 {
+	return math.Mod(math.Mod(bearing+declination, 360)+360, 360)
+}
@@ -0,0 +1,120 @@
+package geo
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A sample of NOAA's published test values for the embedded model
+// (WMM2020_TEST_VALUES.txt): every seventh point, spanning the five years
+// and the poles
+var wmmTestValues = []struct {
+	year, heightKm, lat, lon float64
+	decl                     float64 // degrees
+	x, y, z                  float64 // nT
+}{
+	{2020.0, 28, 89, -121, -112.41, -575.7, -1396.0, 56082.3},
+	{2020.0, 94, -29, -110, 15.82, 23467.8, 6650.9, -19320.7},
+	{2020.5, 8, -52, -75, 15.39, 19571.7, 5387.5, -23769.0},
+	{2021.0, 46, -24, -122, 14.02, 26037.0, 6501.8, -18297.4},
+	{2021.0, 34, -19, 43, -14.32, 19327.6, -4933.5, -25969.5},
+	{2021.5, 12, 33, -145, 12.36, 24301.2, 5327.4, 32429.3},
+	{2022.0, 44, 22, 174, 6.56, 28671.0, 3294.9, 17967.2},
+	{2022.0, 67, -47, -32, -14.09, 12663.7, -3178.2, -20600.9},
+	{2022.5, 96, -46, -85, 18.38, 19136.4, 6358.2, -21915.4},
+	{2023.0, 86, -85, -79, 41.76, 12598.0, 11248.0, -47331.2},
+	{2023.5, 28, 54, -120, 16.20, 14556.6, 4230.3, 52945.6},
+	{2023.5, 59, 32, 163, 0.36, 28170.0, 176.0, 26318.3},
+	{2024.0, 57, 34, -13, -2.62, 28158.7, -1290.8, 29015.5},
+	{2024.5, 93, -2, 158, 7.16, 33858.1, 4253.5, -10940.3},
+	{2024.5, 33, 17, 5, 0.61, 34060.9, 362.9, 8230.6},
+}
+
+func TestWMMTestValues(t *testing.T) {
+	model, err := parseCOF(wmmCOF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.name != "WMM-2020" {
+		t.Fatalf("embedded model is %s; update the test values with it", model.name)
+	}
+
+	for _, tt := range wmmTestValues {
+		x, y, z := model.field(tt.lat, tt.lon, tt.heightKm, tt.year)
+		decl := deg(math.Atan2(y, x))
+		// the published values are rounded to 0.01° and 0.1 nT
+		if math.Abs(decl-tt.decl) > 0.01 {
+			t.Errorf("%.1f %v,%v %vkm: declination %.3f, want %.2f", tt.year, tt.lat, tt.lon, tt.heightKm, decl, tt.decl)
+		}
+		for _, c := range []struct {
+			name      string
+			got, want float64
+		}{{"X", x, tt.x}, {"Y", y, tt.y}, {"Z", z, tt.z}} {
+			if math.Abs(c.got-c.want) > 0.15 {
+				t.Errorf("%.1f %v,%v %vkm: %s %.2f nT, want %.1f", tt.year, tt.lat, tt.lon, tt.heightKm, c.name, c.got, c.want)
+			}
+		}
+	}
+}
+
+func TestDeclination(t *testing.T) {
+	// 2022.0 is 1 January; the published point is at 44 km
+	got, err := Declination(22, 174, 44, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got-6.56) > 0.01 {
+		t.Errorf("Declination = %.3f, want 6.56", got)
+	}
+}
+
+func TestUseModel(t *testing.T) {
+	t.Cleanup(func() { wmm, wmmErr = parseCOF(wmmCOF) })
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.COF")
+	if err := os.WriteFile(bad, []byte("2025.0 WMM-2025\n 1 0 oops\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := UseModel(bad); err == nil {
+		t.Error("UseModel accepted a malformed file")
+	}
+	if err := UseModel(filepath.Join(dir, "missing.COF")); err == nil {
+		t.Error("UseModel accepted a missing file")
+	}
+
+	// the embedded coefficients under a later epoch stand in for a new release
+	renamed := bytes.Replace(wmmCOF, []byte("2020.0            WMM-2020"), []byte("2025.0            WMM-2025"), 1)
+	path := filepath.Join(dir, "WMM.COF")
+	if err := os.WriteFile(path, renamed, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := UseModel(path); err != nil {
+		t.Fatal(err)
+	}
+	name, from, to, err := MagneticModel()
+	if err != nil || name != "WMM-2025" || from != 2025 || to != 2030 {
+		t.Errorf("MagneticModel = %s %v-%v, %v", name, from, to, err)
+	}
+}
+
+func TestTrueMagnetic(t *testing.T) {
+	tests := []struct{ bearing, decl, mag float64 }{
+		{100, 5, 95},
+		{2, 5, 357},
+		{358, -5, 3},
+		{0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := TrueToMagnetic(tt.bearing, tt.decl); math.Abs(got-tt.mag) > 1e-9 {
+			t.Errorf("TrueToMagnetic(%v, %v) = %v, want %v", tt.bearing, tt.decl, got, tt.mag)
+		}
+		if got := MagneticToTrue(tt.mag, tt.decl); math.Abs(got-tt.bearing) > 1e-9 {
+			t.Errorf("MagneticToTrue(%v, %v) = %v, want %v", tt.mag, tt.decl, got, tt.bearing)
+		}
+	}
+}
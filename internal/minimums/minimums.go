@@ -0,0 +1,179 @@
+// 'minimums' checks observed and forecast conditions against the pilot's
+// personal minimums.
+package minimums
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/category"
+	"github.com/house-holder/pilot-bar/internal/config"
+	"github.com/house-holder/pilot-bar/internal/forecast"
+	"github.com/house-holder/pilot-bar/internal/geo"
+	"github.com/house-holder/pilot-bar/internal/parse"
+	"github.com/house-holder/pilot-bar/internal/runways"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// Configured reports whether any minimum is set
+func Configured(cfg config.MinimumsCfg) bool {
+	return cfg.Ceiling > 0 || cfg.Visibility > 0 || cfg.MaxWind > 0 ||
+		cfg.MaxGustSpread > 0 || cfg.MaxCrosswind > 0 ||
+		cfg.NoFreezingPrecip || cfg.DayVFROnly
+}
+
+// conditions are the elements a minimum can be checked against; nil means
+// not reported
+type conditions struct {
+	ceiling  *types.Feet
+	vis      *types.Visibility
+	wind     *types.WindData
+	freezing bool
+	fltCat   string
+	daylight bool
+
+	crosswind   *types.Knots // on the best-aligned runway, gusts included
+	crossRunway string
+}
+
+// violation is one busted limit; limit names it so the TAF reports each
+// only once
+type violation struct {
+	limit string
+	text  string
+}
+
+// Evaluate lists the limits the METAR busts, then those the TAF busts
+// within cfg.TAFHours of now, each at the first hour it happens
+func Evaluate(cfg config.MinimumsCfg, ap types.Airport, now time.Time) []string {
+	violations := []string{}
+	seen := map[string]bool{}
+
+	for _, v := range check(cfg, observed(ap, now)) {
+		seen[v.limit] = true
+		violations = append(violations, v.text)
+	}
+
+	for _, resolved := range forecast.Hourly(ap.TAF, now, cfg.TAFHours) {
+		at := time.Unix(resolved.Time, 0)
+		for _, v := range check(cfg, forecasted(ap, resolved.Worst, at)) {
+			if seen[v.limit] {
+				continue
+			}
+			seen[v.limit] = true
+			violations = append(violations, fmt.Sprintf("TAF %s: %s", at.UTC().Format("1504Z"), v.text))
+		}
+	}
+	return violations
+}
+
+func observed(ap types.Airport, now time.Time) conditions {
+	m := ap.METAR
+	c := conditions{
+		ceiling:  category.Ceiling(m.Clouds, m.VertVis),
+		vis:      m.Visibility,
+		wind:     &m.Wind,
+		fltCat:   m.Category(),
+		daylight: daylight(ap, now),
+	}
+	for _, wx := range m.Weather {
+		c.freezing = c.freezing || wx.FreezingPrecip
+	}
+	if len(ap.Runways) > 0 {
+		c.setCrosswind(ap.Runways[0])
+	}
+	return c
+}
+
+func forecasted(ap types.Airport, f forecast.Conditions, at time.Time) conditions {
+	c := conditions{
+		ceiling:  forecast.Ceiling(f),
+		vis:      f.Visibility,
+		wind:     f.Wind,
+		fltCat:   f.FltCat,
+		daylight: daylight(ap, at),
+	}
+	weather, _ := parse.DecodeWeather(f.WxString)
+	for _, wx := range weather {
+		c.freezing = c.freezing || wx.FreezingPrecip
+	}
+	if f.Wind != nil {
+		list, _ := runways.For(ap.ICAO)
+		if winds := runways.Components(list, *f.Wind, magVar(ap)); len(winds) > 0 {
+			c.setCrosswind(winds[0])
+		}
+	}
+	return c
+}
+
+func (c *conditions) setCrosswind(rw types.RunwayWind) {
+	crosswind := rw.Crosswind
+	if rw.GustCrosswind != nil {
+		crosswind = *rw.GustCrosswind
+	}
+	c.crosswind, c.crossRunway = &crosswind, rw.Runway
+}
+
+func magVar(ap types.Airport) float64 {
+	if ap.MagVar == nil {
+		return 0
+	}
+	return *ap.MagVar
+}
+
+// daylight is assumed when the airport's position isn't known yet
+func daylight(ap types.Airport, t time.Time) bool {
+	if ap.Lat == 0 && ap.Lon == 0 {
+		return true
+	}
+	return geo.IsDaylight(ap.Lat, ap.Lon, t)
+}
+
+// check compares one set of conditions with the minimums
+func check(cfg config.MinimumsCfg, c conditions) []violation {
+	var out []violation
+	add := func(limit, format string, args ...any) {
+		out = append(out, violation{limit: limit, text: fmt.Sprintf(format, args...)})
+	}
+
+	if cfg.Ceiling > 0 && c.ceiling != nil && int(*c.ceiling) < cfg.Ceiling {
+		add("ceiling", "ceiling %d ft below %d ft", *c.ceiling, cfg.Ceiling)
+	}
+	if cfg.Visibility > 0 && c.vis != nil && c.vis.Less(cfg.Visibility) {
+		add("visibility", "visibility %s below %s SM", c.vis, miles(cfg.Visibility))
+	}
+
+	if w := c.wind; w != nil {
+		peak := w.Speed
+		if w.Gusts != nil {
+			peak = *w.Gusts
+		}
+		if cfg.MaxWind > 0 && int(peak) > cfg.MaxWind {
+			add("wind", "wind %d kt above %d kt", peak, cfg.MaxWind)
+		}
+		if cfg.MaxGustSpread > 0 && w.Gusts != nil && int(*w.Gusts-w.Speed) > cfg.MaxGustSpread {
+			add("gust spread", "gust spread %d kt above %d kt", *w.Gusts-w.Speed, cfg.MaxGustSpread)
+		}
+	}
+
+	if cfg.MaxCrosswind > 0 && c.crosswind != nil && int(*c.crosswind) > cfg.MaxCrosswind {
+		add("crosswind", "crosswind %d kt on %s above %d kt", *c.crosswind, c.crossRunway, cfg.MaxCrosswind)
+	}
+	if cfg.NoFreezingPrecip && c.freezing {
+		add("freezing", "freezing precipitation")
+	}
+	if cfg.DayVFROnly {
+		if !c.daylight {
+			add("night", "night, day only")
+		}
+		if c.fltCat != "" && c.fltCat != "VFR" {
+			add("vfr", "%s, VFR only", c.fltCat)
+		}
+	}
+	return out
+}
+
+func miles(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,162 @@
+package minimums
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/category"
+	"github.com/house-holder/pilot-bar/internal/config"
+	"github.com/house-holder/pilot-bar/internal/parse"
+	"github.com/house-holder/pilot-bar/internal/runways"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// KSGF, whose runways are 02/20 and 14/32; 1800Z is early afternoon there
+var (
+	noon  = time.Date(2025, 10, 25, 18, 0, 0, 0, time.UTC)
+	night = time.Date(2025, 10, 26, 4, 0, 0, 0, time.UTC)
+)
+
+const quietTAF = "TAF KSGF 251720Z 2518/2618 12008KT P6SM SCT050"
+
+// airport builds KSGF the way the daemon caches it
+func airport(t *testing.T, rawMETAR, rawTAF string) types.Airport {
+	t.Helper()
+	metar, err := parse.ParseMETAR(rawMETAR, noon)
+	if err != nil {
+		t.Fatalf("%s: %v", rawMETAR, err)
+	}
+	metar.Weather, _ = parse.DecodeWeather(metar.WxString)
+	metar.FltCatDerived = category.FAA.METAR(metar)
+
+	taf, err := parse.ParseTAF(rawTAF, noon)
+	if err != nil {
+		t.Fatalf("%s: %v", rawTAF, err)
+	}
+	list, err := runways.For("KSGF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.Airport{
+		ICAO:    "KSGF",
+		Lat:     37.2398,
+		Lon:     -93.3899,
+		METAR:   metar,
+		TAF:     taf,
+		Runways: runways.Components(list, metar.Wind, 0),
+	}
+}
+
+func TestEvaluateMETAR(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.MinimumsCfg
+		metar string
+		now   time.Time
+		want  []string
+	}{
+		{"ceiling", config.MinimumsCfg{Ceiling: 1000},
+			"METAR KSGF 251752Z 12008KT 10SM OVC008 13/09 A3010", noon,
+			[]string{"ceiling 800 ft below 1000 ft"}},
+		{"ceiling at the limit", config.MinimumsCfg{Ceiling: 1000},
+			"METAR KSGF 251752Z 12008KT 10SM OVC010 13/09 A3010", noon, []string{}},
+		{"vertical visibility as ceiling", config.MinimumsCfg{Ceiling: 1000},
+			"METAR KSGF 251752Z 12008KT 1/4SM FG VV002 13/13 A3010", noon,
+			[]string{"ceiling 200 ft below 1000 ft"}},
+		{"visibility", config.MinimumsCfg{Visibility: 3},
+			"METAR KSGF 251752Z 12008KT 2 1/2SM BR BKN060 13/12 A3010", noon,
+			[]string{"visibility 2 1/2SM below 3 SM"}},
+		{"peak wind is the gust", config.MinimumsCfg{MaxWind: 25},
+			"METAR KSGF 251752Z 12015G28KT 10SM BKN060 13/09 A3010", noon,
+			[]string{"wind 28 kt above 25 kt"}},
+		{"steady wind within the limit", config.MinimumsCfg{MaxWind: 25},
+			"METAR KSGF 251752Z 12025KT 10SM BKN060 13/09 A3010", noon, []string{}},
+		{"gust spread", config.MinimumsCfg{MaxGustSpread: 10},
+			"METAR KSGF 251752Z 12015G28KT 10SM BKN060 13/09 A3010", noon,
+			[]string{"gust spread 13 kt above 10 kt"}},
+		// runway 20 is best aligned: 10 kt across, 15 kt in the gusts
+		{"gust crosswind on the best runway", config.MinimumsCfg{MaxCrosswind: 12},
+			"METAR KSGF 251752Z 23020G30KT 10SM BKN060 13/09 A3010", noon,
+			[]string{"crosswind 15 kt on 20 above 12 kt"}},
+		{"freezing precipitation", config.MinimumsCfg{NoFreezingPrecip: true},
+			"METAR KSGF 251752Z 12008KT 3SM -FZRA OVC040 M01/M03 A3010", noon,
+			[]string{"freezing precipitation"}},
+		{"freezing fog isn't precipitation", config.MinimumsCfg{NoFreezingPrecip: true},
+			"METAR KSGF 251752Z 12008KT 1/2SM FZFG OVC040 M01/M01 A3010", noon, []string{}},
+		{"night", config.MinimumsCfg{DayVFROnly: true},
+			"METAR KSGF 260352Z 12008KT 10SM BKN060 13/09 A3010", night,
+			[]string{"night, day only"}},
+		{"day VFR only", config.MinimumsCfg{DayVFROnly: true},
+			"METAR KSGF 251752Z 12008KT 10SM OVC008 13/09 A3010", noon,
+			[]string{"IFR, VFR only"}},
+		{"day VFR", config.MinimumsCfg{DayVFROnly: true},
+			"METAR KSGF 251752Z 12008KT 10SM BKN060 13/09 A3010", noon, []string{}},
+	}
+	for _, tt := range tests {
+		ap := airport(t, tt.metar, quietTAF)
+		if got := Evaluate(tt.cfg, ap, tt.now); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Evaluate = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateTAF(t *testing.T) {
+	const metar = "METAR KSGF 251752Z 12008KT 10SM BKN060 13/09 A3010"
+	const taf = "TAF KSGF 251720Z 2518/2618 12008KT P6SM SCT050 " +
+		"FM252000 23020G30KT 5SM -RA BKN030 " +
+		"FM252200 36012KT 2SM -FZRA OVC008"
+	cfg := config.MinimumsCfg{
+		Ceiling:          1000,
+		Visibility:       3,
+		MaxWind:          25,
+		MaxGustSpread:    8,
+		MaxCrosswind:     12,
+		NoFreezingPrecip: true,
+		DayVFROnly:       true,
+		TAFHours:         6,
+	}
+	want := []string{
+		"TAF 2000Z: wind 30 kt above 25 kt",
+		"TAF 2000Z: gust spread 10 kt above 8 kt",
+		"TAF 2000Z: crosswind 15 kt on 20 above 12 kt",
+		"TAF 2000Z: MVFR, VFR only",
+		"TAF 2200Z: ceiling 800 ft below 1000 ft",
+		"TAF 2200Z: visibility 2SM below 3 SM",
+		"TAF 2200Z: freezing precipitation",
+	}
+	got := Evaluate(cfg, airport(t, metar, taf), noon)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Evaluate =\n%q\nwant\n%q", got, want)
+	}
+
+	// beyond TAFHours the forecast isn't checked
+	cfg.TAFHours = 2
+	if got := Evaluate(cfg, airport(t, metar, taf), noon); len(got) != 0 {
+		t.Errorf("TAFHours 2: %q", got)
+	}
+}
+
+func TestEvaluateNotRepeated(t *testing.T) {
+	// the METAR already busts the ceiling the TAF goes on to forecast
+	ap := airport(t, "METAR KSGF 251752Z 12008KT 10SM OVC008 13/09 A3010",
+		"TAF KSGF 251720Z 2518/2618 12008KT P6SM OVC008 FM252100 12008KT P6SM OVC005")
+	cfg := config.MinimumsCfg{Ceiling: 1000, TAFHours: 6}
+	want := []string{"ceiling 800 ft below 1000 ft"}
+	if got := Evaluate(cfg, ap, noon); !reflect.DeepEqual(got, want) {
+		t.Errorf("Evaluate = %q, want %q", got, want)
+	}
+}
+
+func TestUnsetMinimums(t *testing.T) {
+	ap := airport(t, "METAR KSGF 260352Z 23035G50KT 1/4SM +FZRA VV001 M01/M01 A3010",
+		"TAF KSGF 251720Z 2518/2618 23035G50KT 1/4SM +FZRA VV001")
+	var cfg config.MinimumsCfg
+	if Configured(cfg) {
+		t.Error("zero minimums reported as configured")
+	}
+	cfg.TAFHours = 12
+	if got := Evaluate(cfg, ap, night); len(got) != 0 {
+		t.Errorf("unset minimums reported %q", got)
+	}
+}
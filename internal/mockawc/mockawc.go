@@ -0,0 +1,278 @@
+// 'mockawc' imitates the aviationweather.gov data API from the API-shaped
+// JSON in testdata/, with scriptable failures for exercising retry paths.
+package mockawc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPath is the prefix to append to a server URL to get a client BaseURL
+const APIPath = "/api/data"
+
+// Endpoints served, each backed by <name>*.json in the fixture dir
+var Endpoints = []string{"metar", "taf", "pirep", "airsigmet", "gairmet", "stationinfo"}
+
+type FailureKind int
+
+const (
+	FailStatus    FailureKind = iota // respond with Status (+ Retry-After)
+	FailTimeout                      // hold the request for Delay, send nothing
+	FailEmpty                        // 200 with an empty array
+	FailMalformed                    // 200 with a truncated body
+)
+
+// Failure replaces one response on an endpoint
+type Failure struct {
+	Kind       FailureKind
+	Status     int
+	RetryAfter string
+	Delay      time.Duration
+}
+
+func Status(code int) Failure { return Failure{Kind: FailStatus, Status: code} }
+
+func TooManyRequests(retryAfter string) Failure {
+	return Failure{Kind: FailStatus, Status: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+func Unavailable(retryAfter string) Failure {
+	return Failure{Kind: FailStatus, Status: http.StatusServiceUnavailable, RetryAfter: retryAfter}
+}
+
+func Timeout(delay time.Duration) Failure { return Failure{Kind: FailTimeout, Delay: delay} }
+
+func Empty() Failure { return Failure{Kind: FailEmpty} }
+
+func Malformed() Failure { return Failure{Kind: FailMalformed} }
+
+// Handler serves the API endpoints. Queued failures are consumed one per
+// request, after which responses come from the fixtures again.
+type Handler struct {
+	dir string
+
+	mu     sync.Mutex
+	script map[string][]Failure
+	hits   map[string]int
+}
+
+func NewHandler(dir string) *Handler {
+	return &Handler{
+		dir:    dir,
+		script: map[string][]Failure{},
+		hits:   map[string]int{},
+	}
+}
+
+// Fail queues failures for endpoint ("metar", "taf", ...)
+func (h *Handler) Fail(endpoint string, failures ...Failure) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.script[endpoint] = append(h.script[endpoint], failures...)
+}
+
+// Hits reports how many requests endpoint has received
+func (h *Handler) Hits(endpoint string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hits[endpoint]
+}
+
+// Reset clears queued failures and hit counts
+func (h *Handler) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.script = map[string][]Failure{}
+	h.hits = map[string]int{}
+}
+
+func (h *Handler) next(endpoint string) (Failure, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hits[endpoint]++
+	queue := h.script[endpoint]
+	if len(queue) == 0 {
+		return Failure{}, false
+	}
+	h.script[endpoint] = queue[1:]
+	return queue[0], true
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := strings.CutPrefix(r.URL.Path, APIPath+"/")
+	if !ok || !slices.Contains(Endpoints, endpoint) {
+		http.NotFound(w, r)
+		return
+	}
+
+	if failure, ok := h.next(endpoint); ok {
+		writeFailure(w, r, failure)
+		return
+	}
+
+	records, err := h.load(endpoint)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	records = filterIDs(records, r.URL.Query())
+	records = filterBBox(records, r.URL.Query())
+	if len(records) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+func writeFailure(w http.ResponseWriter, r *http.Request, f Failure) {
+	switch f.Kind {
+	case FailTimeout:
+		select {
+		case <-r.Context().Done():
+		case <-time.After(f.Delay):
+		}
+	case FailEmpty:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	case FailMalformed:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"icaoId": "KCGI", "obsTime": `))
+	default:
+		if f.RetryAfter != "" {
+			w.Header().Set("Retry-After", f.RetryAfter)
+		}
+		http.Error(w, http.StatusText(f.Status), f.Status)
+	}
+}
+
+// load merges every <endpoint>*.json array; a missing fixture is empty
+func (h *Handler) load(endpoint string) ([]map[string]any, error) {
+	paths, err := filepath.Glob(filepath.Join(h.dir, endpoint+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	records := []map[string]any{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var items []map[string]any
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", path, err)
+		}
+		records = append(records, items...)
+	}
+	return records, nil
+}
+
+// filterIDs applies the ids= station filter the real API supports
+func filterIDs(records []map[string]any, query map[string][]string) []map[string]any {
+	ids := query["ids"]
+	if len(ids) == 0 {
+		return records
+	}
+
+	wanted := map[string]bool{}
+	for _, param := range ids {
+		for id := range strings.SplitSeq(param, ",") {
+			wanted[strings.ToUpper(strings.TrimSpace(id))] = true
+		}
+	}
+
+	filtered := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		if id, _ := record["icaoId"].(string); wanted[strings.ToUpper(id)] {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// filterBBox applies the bbox=minLat,minLon,maxLat,maxLon area filter
+func filterBBox(records []map[string]any, query map[string][]string) []map[string]any {
+	param := query["bbox"]
+	if len(param) == 0 {
+		return records
+	}
+	var box [4]float64
+	parts := strings.Split(param[0], ",")
+	if len(parts) != 4 {
+		return records
+	}
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return records
+		}
+		box[i] = v
+	}
+
+	filtered := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		lat, latOK := record["lat"].(float64)
+		lon, lonOK := record["lon"].(float64)
+		if latOK && lonOK && lat >= box[0] && lat <= box[2] && lon >= box[1] && lon <= box[3] {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// Server is a Handler on an httptest server, for use from tests
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// New starts a server over the fixtures in dir; Close it when done
+func New(dir string) *Server {
+	h := NewHandler(dir)
+	return &Server{Server: httptest.NewServer(h), Handler: h}
+}
+
+// BaseURL is the value for fetch.Client.BaseURL
+func (s *Server) BaseURL() string {
+	return s.URL + APIPath
+}
+
+// ParseFailure reads the CLI shorthand for a failure: an HTTP status code
+// ("500"), "429:30" / "503:30" for a status with Retry-After seconds,
+// "timeout:10s", "empty" or "malformed"
+func ParseFailure(spec string) (Failure, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "timeout":
+		delay := time.Minute
+		if arg != "" {
+			d, err := time.ParseDuration(arg)
+			if err != nil {
+				return Failure{}, fmt.Errorf("failure %q: %w", spec, err)
+			}
+			delay = d
+		}
+		return Timeout(delay), nil
+	case "empty":
+		return Empty(), nil
+	case "malformed":
+		return Malformed(), nil
+	}
+
+	var code int
+	if _, err := fmt.Sscanf(kind, "%d", &code); err != nil || code < 100 || code > 599 {
+		return Failure{}, fmt.Errorf("failure %q: unknown kind", spec)
+	}
+	return Failure{Kind: FailStatus, Status: code, RetryAfter: arg}, nil
+}
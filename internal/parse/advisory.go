@@ -0,0 +1,150 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// G-AIRMET snapshots are 3 hours apart; each stands for the half-interval
+// either side of its valid time
+const gairmetHalfSpan = 90 * time.Minute
+
+var gairmetHazards = map[string]string{
+	"IFR":     "IFR",
+	"MT_OBSC": "MTN OBSC",
+	"TURB-HI": "TURB",
+	"TURB-LO": "TURB",
+	"ICE":     "ICE",
+	"LLWS":    "LLWS",
+	"SFC_WND": "SFC WND",
+}
+
+// BuildInternalAirSigmet normalizes one airsigmet record. Outlooks are not
+// active hazards and are rejected.
+func BuildInternalAirSigmet(data *types.AirSigmetResponse, output *types.Advisory) error {
+	if data.AirSigmetType == "OUTLOOK" {
+		return fmt.Errorf("BuildInternalAirSigmet: outlook is not an advisory")
+	}
+
+	output.Product = data.AirSigmetType
+	output.Hazard = data.Hazard
+	switch data.Hazard {
+	case "CONVECTIVE":
+		output.Product = "CONVECTIVE SIGMET"
+	case "MTN OBSCN":
+		output.Hazard = "MTN OBSC"
+	}
+	output.ValidFrom = data.ValidTimeFrom
+	output.ValidTo = data.ValidTimeTo
+	output.Raw = data.RawAirSigmet
+
+	// a second low/high pair describes a sloping boundary; keep the
+	// envelope
+	output.Base = lowestFeet(data.AltitudeLow1, data.AltitudeLow2)
+	output.Top = highestFeet(data.AltitudeHi1, data.AltitudeHi2)
+	return nil
+}
+
+// GAirmetHazard reports whether a G-AIRMET hazard is one kept as an
+// advisory; freezing-level contours are not
+func GAirmetHazard(hazard string) bool {
+	_, ok := gairmetHazards[hazard]
+	return ok
+}
+
+// BuildInternalGAirmet normalizes one G-AIRMET snapshot
+func BuildInternalGAirmet(data *types.GAirmetResponse, output *types.Advisory) error {
+	hazard, ok := gairmetHazards[data.Hazard]
+	if !ok {
+		return fmt.Errorf("BuildInternalGAirmet: unsupported hazard %q", data.Hazard)
+	}
+	output.Product = "G-AIRMET"
+	output.Hazard = hazard
+	output.Detail = data.DueTo
+	switch data.Hazard {
+	case "TURB-HI":
+		output.Detail = strings.TrimSpace("high " + output.Detail)
+	case "TURB-LO":
+		output.Detail = strings.TrimSpace("low " + output.Detail)
+	}
+	output.Raw = fmt.Sprintf("%s %s %s", data.Product, data.Tag, data.Hazard)
+
+	valid, err := time.Parse(time.RFC3339, data.ValidTime)
+	if err != nil {
+		return fmt.Errorf("BuildInternalGAirmet validTime: %w", err)
+	}
+	output.ValidFrom = valid.Add(-gairmetHalfSpan).Unix()
+	output.ValidTo = valid.Add(gairmetHalfSpan).Unix()
+
+	if output.Base, err = gairmetHeight(data.Base); err != nil {
+		return fmt.Errorf("BuildInternalGAirmet base: %w", err)
+	}
+	if output.Top, err = gairmetHeight(data.Top); err != nil {
+		return fmt.Errorf("BuildInternalGAirmet top: %w", err)
+	}
+	if s, _ := data.Base.(string); s == "FZL" {
+		output.BaseFZL = true
+	}
+	return nil
+}
+
+// gairmetHeight reads hundreds of feet as a number, "180" or "FL180";
+// "SFC" and "FZL" have no fixed height
+func gairmetHeight(value any) (*types.Feet, error) {
+	var hundreds int
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case float64:
+		hundreds = int(v)
+	case string:
+		v = strings.TrimPrefix(strings.TrimSpace(v), "FL")
+		if v == "" || v == "SFC" || v == "FZL" {
+			return nil, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("height %q: %w", value, errMalformed)
+		}
+		hundreds = n
+	default:
+		return nil, fmt.Errorf("height %v: unexpected type %T", value, value)
+	}
+	if hundreds <= 0 {
+		return nil, nil
+	}
+	height := types.Feet(hundreds * 100)
+	return &height, nil
+}
+
+func lowestFeet(values ...*int) *types.Feet {
+	var low *types.Feet
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		if *v == 0 {
+			return nil // surface
+		}
+		if low == nil || types.Feet(*v) < *low {
+			height := types.Feet(*v)
+			low = &height
+		}
+	}
+	return low
+}
+
+func highestFeet(values ...*int) *types.Feet {
+	var high *types.Feet
+	for _, v := range values {
+		if v != nil && (high == nil || types.Feet(*v) > *high) {
+			height := types.Feet(*v)
+			high = &height
+		}
+	}
+	return high
+}
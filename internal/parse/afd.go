@@ -0,0 +1,75 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// section headers look like ".SYNOPSIS...", ".NEAR TERM /THROUGH TONIGHT/...",
+// ".SHORT TERM (Tonight through Friday)..." or ".AVIATION (18Z TAFS)...",
+// with the text often starting on the same line
+var afdHeaderRe = regexp.MustCompile(`^\.([A-Z][A-Z0-9 &,'-]*?)\s*(?:/([^/]*)/|\(([^)]*)\))?\s*\.\.\.\s*(.*)$`)
+
+// BuildInternalAFD splits the product text into its sections
+func BuildInternalAFD(data *types.AFDresponse, output *types.AFD) error {
+	output.ID = data.ID
+	output.Office = data.IssuingOffice
+	output.Raw = data.ProductText
+
+	issued, err := time.Parse(time.RFC3339, data.IssuanceTime)
+	if err != nil {
+		return fmt.Errorf("BuildInternalAFD issuanceTime: %w", err)
+	}
+	output.Issued = issued.Unix()
+	output.Sections = ParseAFDSections(data.ProductText)
+	if len(output.Sections) == 0 {
+		return fmt.Errorf("BuildInternalAFD %s: no sections found", data.ID)
+	}
+	return nil
+}
+
+// ParseAFDSections returns the dot-headed sections in product order. A
+// section ends at the next header, "&&" or "$$".
+func ParseAFDSections(text string) []types.AFDSection {
+	var sections []types.AFDSection
+	var current *types.AFDSection
+	var body []string
+
+	flush := func() {
+		if current != nil {
+			current.Text = strings.TrimSpace(strings.Join(body, "\n"))
+			sections = append(sections, *current)
+		}
+		current, body = nil, nil
+	}
+
+	for line := range strings.Lines(strings.ReplaceAll(text, "\r\n", "\n")) {
+		line = strings.TrimRight(line, " \n")
+		if m := afdHeaderRe.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &types.AFDSection{
+				Name:   strings.TrimSpace(m[1]),
+				Period: strings.TrimSpace(m[2] + m[3]),
+			}
+			if m[4] != "" {
+				body = append(body, m[4])
+			}
+			continue
+		}
+
+		switch strings.TrimSpace(line) {
+		case "&&", "$$":
+			flush()
+			continue
+		}
+		if current != nil {
+			body = append(body, line)
+		}
+	}
+	flush()
+	return sections
+}
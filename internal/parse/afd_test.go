@@ -0,0 +1,93 @@
+package parse
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func TestAFDSectionsTestdata(t *testing.T) {
+	raw, err := os.ReadFile("../../testdata/afd.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []types.AFDresponse
+	if err := json.Unmarshal(raw, &records); err != nil {
+		t.Fatal(err)
+	}
+
+	var afd types.AFD
+	if err := BuildInternalAFD(&records[0], &afd); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ name, period string }{
+		{"KEY MESSAGES", ""},
+		{"SYNOPSIS", ""},
+		{"NEAR TERM", "THROUGH TONIGHT"},
+		{"SHORT TERM", "SUNDAY THROUGH MONDAY"},
+		{"LONG TERM", "TUESDAY THROUGH SATURDAY"},
+		{"AVIATION", "18Z TAFS THROUGH 18Z SUNDAY"},
+	}
+	if len(afd.Sections) != len(want) {
+		t.Fatalf("%d sections, want %d: %+v", len(afd.Sections), len(want), afd.Sections)
+	}
+	for i, w := range want {
+		if s := afd.Sections[i]; s.Name != w.name || s.Period != w.period {
+			t.Errorf("section %d = %q /%s/, want %q /%s/", i, s.Name, s.Period, w.name, w.period)
+		}
+	}
+	if aviation := afd.Sections[5].Text; !strings.HasPrefix(aviation, "Issued at 1240 PM CDT") ||
+		!strings.HasSuffix(aviation, "after 15Z Sunday.") {
+		t.Errorf("AVIATION text = %q", aviation)
+	}
+}
+
+// offices that have moved to the newer layout put the period in
+// parentheses, and often start the text on the header line
+const afdParenthesized = `.KEY MESSAGES...
+
+- Showers and a few thunderstorms this evening.
+
+&&
+
+.SHORT TERM (Tonight through Friday)...
+Issued at 255 PM CDT Thu Oct 23 2025
+
+A cold front moves through tonight.
+
+.LONG TERM (Friday Night through Wednesday)...Dry and cooler.
+
+&&
+
+.AVIATION (18Z TAFS)...
+Issued at 1245 PM CDT Thu Oct 23 2025
+
+MVFR ceilings lift to VFR by 21Z.
+
+&&
+
+$$
+`
+
+func TestAFDSectionsParenthesized(t *testing.T) {
+	sections := ParseAFDSections(afdParenthesized)
+	want := []types.AFDSection{
+		{Name: "KEY MESSAGES", Text: "- Showers and a few thunderstorms this evening."},
+		{Name: "SHORT TERM", Period: "Tonight through Friday",
+			Text: "Issued at 255 PM CDT Thu Oct 23 2025\n\nA cold front moves through tonight."},
+		{Name: "LONG TERM", Period: "Friday Night through Wednesday", Text: "Dry and cooler."},
+		{Name: "AVIATION", Period: "18Z TAFS",
+			Text: "Issued at 1245 PM CDT Thu Oct 23 2025\n\nMVFR ceilings lift to VFR by 21Z."},
+	}
+	if len(sections) != len(want) {
+		t.Fatalf("%d sections, want %d: %+v", len(sections), len(want), sections)
+	}
+	for i := range want {
+		if sections[i] != want[i] {
+			t.Errorf("section %d:\n got %+v\nwant %+v", i, sections[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+const (
+	ftPerMeter = 3.28084
+	inHgPerHPa = 0.0295300
+)
+
+var (
+	obsTimeRe  = regexp.MustCompile(`^(\d{2})(\d{2})(\d{2})Z$`)
+	rvrRe      = regexp.MustCompile(`^R(\d{2}[LCR]?)/([PM])?(\d{4})(?:V([PM])?(\d{4}))?(FT)?/?([UDN])?$`)
+	rvrLikeRe  = regexp.MustCompile(`^R\d{2}[LCR]?/`)
+	tempDewRe  = regexp.MustCompile(`^(M?\d{2}|//)/(M?\d{2}|//?)?$`)
+	tempLikeRe = regexp.MustCompile(`^M?\d{1,3}/M?\d{0,3}$`)
+	altimRe    = regexp.MustCompile(`^([AQ])(\d{4})$`)
+	altimLike  = regexp.MustCompile(`^[AQ]\d+$`)
+	skyLikeRe  = regexp.MustCompile(`^(FEW|SCT|BKN|OVC|VV)`)
+	windLikeRe = regexp.MustCompile(`(KT|MPS|KMH)$`)
+)
+
+// ParseMETAR decodes the body of a raw METAR or SPECI (everything before
+// RMK) in its standard group order: type, COR, station, time, AUTO/NIL,
+// wind, visibility, RVR, weather, sky, temperature/dew point, altimeter.
+// Groups out of order or not modelled land in NotDecoded; a malformed group
+// fails with a *TokenError naming it. ref is any time near the observation.
+func ParseMETAR(raw string, ref time.Time) (types.METAR, error) {
+	raw = strings.TrimSpace(raw)
+	body, _, _ := strings.Cut(strings.TrimSuffix(raw, "="), " RMK")
+	tokens := strings.Fields(body)
+	output := types.METAR{Raw: raw, Type: "METAR"}
+
+	i := 0
+	next := func() string {
+		if i < len(tokens) {
+			return tokens[i]
+		}
+		return ""
+	}
+
+	if next() == "METAR" || next() == "SPECI" {
+		output.Type = next()
+		i++
+	}
+	if next() == "COR" {
+		output.Corrected = true
+		i++
+	}
+
+	if !stationRe.MatchString(next()) {
+		return types.METAR{}, tokenErr("station", next(), errMalformed)
+	}
+	i++
+
+	m := obsTimeRe.FindStringSubmatch(next())
+	if m == nil {
+		return types.METAR{}, tokenErr("observation time", next(), errMalformed)
+	}
+	observed, err := resolveDayTime(ref, atoi(m[1]), atoi(m[2]), atoi(m[3]))
+	if err != nil {
+		return types.METAR{}, tokenErr("observation time", next(), err)
+	}
+	output.Reported.Epoch = observed.Unix()
+	output.Reported.Zulu = types.Time{Day: uint8(observed.Day()), Hour: uint8(observed.Hour())}
+	i++
+
+	for {
+		switch next() {
+		case "NIL":
+			return output, nil
+		case "AUTO":
+			output.Auto = true
+			i++
+			continue
+		case "COR":
+			output.Corrected = true
+			i++
+			continue
+		}
+		break
+	}
+
+	// each stage consumes its groups, in order; anything a stage doesn't
+	// claim is offered to the later ones
+	stage := 0
+	for i < len(tokens) {
+		token := tokens[i]
+		used, nextStage, err := decodeMETARGroup(&output, tokens, i, stage)
+		if err != nil {
+			return types.METAR{}, err
+		}
+		if used == 0 {
+			output.NotDecoded += " " + token
+			i++
+			continue
+		}
+		i += used
+		stage = nextStage
+	}
+	output.NotDecoded = strings.TrimSpace(output.NotDecoded)
+	if output.Temp.Source == "" && !output.Temp.NoTemp && !output.Temp.NoDewpoint {
+		// no temperature/dewpoint group at all
+		output.Temp.NoTemp, output.Temp.NoDewpoint = true, true
+	}
+	return output, nil
+}
+
+const (
+	stageWind = iota
+	stageVisibility
+	stageRVR
+	stageWeather
+	stageSky
+	stageTemp
+	stageAltimeter
+)
+
+// decodeMETARGroup tries tokens[i] against each group allowed at or after
+// stage, returning the tokens used and the stage reached
+func decodeMETARGroup(output *types.METAR, tokens []string, i, stage int) (int, int, error) {
+	token := tokens[i]
+	if strings.Contains(token, "//") && !tempDewRe.MatchString(token) {
+		// automated stations slash out what a sensor couldn't report
+		return 0, stage, nil
+	}
+
+	if stage <= stageWind {
+		wind, ok, err := parseWindGroup(token)
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok {
+			output.Wind = wind
+			if i+1 < len(tokens) {
+				if from, to, ok := parseWindVariation(tokens[i+1]); ok {
+					output.Wind.Varying = &types.WindVariation{From: from, To: to}
+					return 2, stageVisibility, nil
+				}
+			}
+			return 1, stageVisibility, nil
+		}
+		if windLikeRe.MatchString(token) {
+			return 0, 0, tokenErr("wind", token, errMalformed)
+		}
+	}
+
+	if stage <= stageVisibility {
+		vis, used, err := parseVisibilityGroup(tokens, i)
+		if err != nil {
+			return 0, 0, err
+		}
+		if used > 0 {
+			output.Visibility = &vis
+			if token == "CAVOK" {
+				return used, stageTemp, nil
+			}
+			return used, stageRVR, nil
+		}
+		if strings.HasSuffix(token, "SM") {
+			return 0, 0, tokenErr("visibility", token, errMalformed)
+		}
+	}
+
+	if stage <= stageRVR && rvrLikeRe.MatchString(token) {
+		rvr, err := parseRVRGroup(token)
+		if err != nil {
+			return 0, 0, err
+		}
+		output.RVR = append(output.RVR, rvr)
+		return 1, stageRVR, nil
+	}
+
+	if stage <= stageWeather && isWeatherToken(token) {
+		output.WxString = strings.TrimSpace(output.WxString + " " + token)
+		return 1, stageWeather, nil
+	}
+
+	if stage <= stageSky {
+		sky, ok, err := parseSkyGroup(token)
+		if err != nil {
+			return 0, 0, err
+		}
+		if ok {
+			switch {
+			case sky.clear:
+				output.Clouds = []types.CloudData{}
+			case sky.vertVis != nil:
+				output.VertVis = sky.vertVis
+			default:
+				output.Clouds = append(output.Clouds, *sky.layer)
+			}
+			return 1, stageSky, nil
+		}
+		if skyLikeRe.MatchString(token) {
+			return 0, 0, tokenErr("sky", token, errMalformed)
+		}
+	}
+
+	if stage <= stageTemp {
+		if m := tempDewRe.FindStringSubmatch(token); m != nil {
+			if m[1] == "//" {
+				output.Temp.NoTemp = true
+			} else {
+				output.Temp.Ambient = signedTemp(m[1])
+				output.Temp.Source = "body"
+			}
+			if m[2] == "" || strings.HasPrefix(m[2], "/") {
+				output.Temp.NoDewpoint = true
+			} else {
+				output.Temp.Dewpoint = signedTemp(m[2])
+			}
+			return 1, stageAltimeter, nil
+		}
+		if tempLikeRe.MatchString(token) {
+			return 0, 0, tokenErr("temperature", token, errMalformed)
+		}
+	}
+
+	if stage <= stageAltimeter {
+		if m := altimRe.FindStringSubmatch(token); m != nil {
+			value := float64(atoi(m[2]))
+			if m[1] == "A" {
+				output.Altimeter = types.InHg(value / 100)
+			} else if output.Altimeter == 0 {
+				// keep A over Q when a report carries both
+				output.Altimeter = types.InHg(value * inHgPerHPa)
+			}
+			return 1, stageAltimeter, nil
+		}
+		if altimLike.MatchString(token) {
+			return 0, 0, tokenErr("altimeter", token, errMalformed)
+		}
+	}
+
+	return 0, stage, nil
+}
+
+func parseRVRGroup(token string) (types.RVR, error) {
+	m := rvrRe.FindStringSubmatch(token)
+	if m == nil {
+		return types.RVR{}, tokenErr("RVR", token, errMalformed)
+	}
+	factor := 1.0
+	if m[6] == "" {
+		factor = ftPerMeter // metric RVR has no unit suffix
+	}
+	convert := func(s string) types.Feet {
+		return types.Feet(float64(atoi(s))*factor + 0.5)
+	}
+
+	rvr := types.RVR{
+		Runway: m[1],
+		Feet:   convert(m[3]),
+		Above:  m[2] == "P",
+		Below:  m[2] == "M",
+		Trend:  m[7],
+	}
+	if m[5] != "" {
+		high := convert(m[5])
+		rvr.Max = &high
+		rvr.Above = m[4] == "P"
+	}
+	return rvr, nil
+}
+
+func signedTemp(s string) int {
+	if strings.HasPrefix(s, "M") {
+		return -atoi(s[1:])
+	}
+	return atoi(s)
+}
@@ -0,0 +1,268 @@
+package parse
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/internal/category"
+	"github.com/house-holder/pilot-bar/internal/derive"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func buildMETAR(t *testing.T, raw string, temp, dewp *float64) types.METAR {
+	t.Helper()
+	obs := time.Date(2025, 10, 25, 17, 56, 0, 0, time.UTC)
+	record := types.METARresponse{
+		IcaoID:     "KXYZ",
+		ObsTime:    obs.Unix(),
+		ReportTime: "2025-10-25T18:00:00Z",
+		RawOb:      raw,
+		Temp:       temp,
+		Dewp:       dewp,
+		Altim:      1013,
+	}
+	var metar types.METAR
+	if err := BuildInternalMETAR(&record, &metar); err != nil {
+		t.Fatalf("%s: %v", raw, err)
+	}
+	return metar
+}
+
+func TestMissingDewpoint(t *testing.T) {
+	temp, dewp := -1.0, -3.0
+	tests := []struct {
+		raw        string
+		dewp       *float64
+		noDewpoint bool
+		dewpoint   float64
+	}{
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR M01/ A2992", nil, true, 0},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR M01// A2992", nil, true, 0},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR M01/M03 A2992", &dewp, false, -3},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR M01/ A2992 RMK AO2 T10111033", nil, false, -3.3},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR ///// A2992", nil, true, 0},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR ///// A2992", &dewp, false, -3},
+	}
+	for _, tt := range tests {
+		metar := buildMETAR(t, tt.raw, &temp, tt.dewp)
+		if metar.Temp.NoDewpoint != tt.noDewpoint || metar.Temp.DewpointExact != tt.dewpoint {
+			t.Errorf("%s: %+v", tt.raw, metar.Temp)
+		}
+
+		derived := derive.Compute(1000, metar)
+		if derived == nil {
+			t.Fatalf("%s: nothing derived", tt.raw)
+		}
+		if tt.noDewpoint && (derived.RelHumidity != nil || derived.CloudBase != nil) {
+			t.Errorf("%s: humidity or cloud base from a missing dewpoint", tt.raw)
+		}
+		if !tt.noDewpoint && (derived.RelHumidity == nil || derived.CloudBase == nil) {
+			t.Errorf("%s: humidity or cloud base missing", tt.raw)
+		}
+		if derived.DensityAlt == nil || derived.FreezingLevel == nil {
+			t.Errorf("%s: no density altitude or freezing level", tt.raw)
+		}
+	}
+}
+
+func TestMissingTemperature(t *testing.T) {
+	temp, dewp := 7.0, -3.0
+	tests := []struct {
+		raw        string
+		temp, dewp *float64
+		noTemp     bool
+		noDewpoint bool
+		ambient    float64
+		source     string
+	}{
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR ///M03 A2992", nil, &dewp, true, false, 0, ""},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR ///// A2992", nil, nil, true, true, 0, ""},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR A2992", nil, nil, true, true, 0, ""},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR ///M03 A2992 RMK AO2 T00721033", nil, &dewp, false, false, 7.2, "remarks"},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR ///M03 A2992", &temp, &dewp, false, false, 7, "api"},
+	}
+	for _, tt := range tests {
+		metar := buildMETAR(t, tt.raw, tt.temp, tt.dewp)
+		got := metar.Temp
+		if got.NoTemp != tt.noTemp || got.NoDewpoint != tt.noDewpoint || got.AmbientExact != tt.ambient || got.Source != tt.source {
+			t.Errorf("%s: %+v", tt.raw, got)
+		}
+		if !tt.noDewpoint && got.DewpointExact != -3 && got.DewpointExact != -3.3 {
+			t.Errorf("%s: dewpoint %v", tt.raw, got.DewpointExact)
+		}
+
+		derived := derive.Compute(1000, metar)
+		if derived == nil || derived.PressureAlt == 0 {
+			t.Fatalf("%s: no pressure altitude", tt.raw)
+		}
+		unknown := derived.DensityAlt == nil && derived.FreezingLevel == nil &&
+			derived.RelHumidity == nil && derived.CloudBase == nil
+		if tt.noTemp != unknown {
+			t.Errorf("%s: derived %+v with noTemp=%v", tt.raw, derived, tt.noTemp)
+		}
+	}
+}
+
+// TestCategoryFromBody checks that a body without sky or visibility leaves
+// nothing to categorize; the daemon applies the configured ruleset
+func TestCategoryFromBody(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"METAR KXYZ 251756Z /////KT A2992", ""},
+		{"METAR KXYZ 251756Z AUTO /////KT ////// ////// ///// A2992", ""},
+		{"METAR KXYZ 251756Z 27010KT CLR 12/05 A2992", "VFR"},
+		{"METAR KXYZ 251756Z 27010KT 10SM 12/05 A2992", "VFR"},
+		{"METAR KXYZ 251756Z 27010KT OVC008 12/05 A2992", "IFR"},
+		{"METAR KXYZ 251756Z 27010KT 1/2SM FG VV002 12/12 A2992", "LIFR"},
+	}
+	for _, tt := range tests {
+		metar, err := ParseMETAR(tt.raw, time.Date(2025, 10, 25, 18, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.raw, err)
+		}
+		if got := category.FAA.METAR(metar); got != tt.want {
+			t.Errorf("%s: category = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestGustFromAPI(t *testing.T) {
+	dir, speed, gust := types.WindDir{Degrees: 210}, types.Speed(22), types.Speed(31)
+	record := types.METARresponse{
+		IcaoID:     "KAMA",
+		ObsTime:    time.Date(2025, 10, 25, 17, 53, 0, 0, time.UTC).Unix(),
+		ReportTime: "2025-10-25T18:00:00Z",
+		RawOb:      "METAR KAMA 251753Z 21022G3XKT 10SM FEW250 23/03 A2981",
+		Wdir:       &dir,
+		Wspd:       &speed,
+		Wgst:       &gust,
+	}
+	var metar types.METAR
+	if err := BuildInternalMETAR(&record, &metar); err != nil {
+		t.Fatal(err)
+	}
+	wind := metar.Wind
+	if wind.Direction != 210 || wind.Speed != 22 || wind.Gusts == nil || *wind.Gusts != 31 {
+		t.Errorf("wind = %+v, want the API's 210 at 22 gusting 31", wind)
+	}
+}
+
+func TestParseMETARGroups(t *testing.T) {
+	ref := time.Date(2025, 10, 25, 18, 0, 0, 0, time.UTC)
+	knots := func(k types.Knots) *types.Knots { return &k }
+	feet := func(f types.Feet) *types.Feet { return &f }
+
+	tests := []struct {
+		name  string
+		raw   string
+		check func(m types.METAR) bool
+	}{
+		{"variable sector", "METAR KXYZ 251756Z 31015G25KT 280V340 10SM CLR 12/05 A2992", func(m types.METAR) bool {
+			w := m.Wind
+			return w.Direction == 310 && w.Speed == 15 && *w.Gusts == 25 &&
+				*w.Varying == types.WindVariation{From: 280, To: 340} && m.NotDecoded == ""
+		}},
+		{"MPS", "METAR EGLL 251750Z 24008G12MPS 9999 FEW030 12/05 Q1013", func(m types.METAR) bool {
+			return m.Wind.Direction == 240 && m.Wind.Speed == 16 && *m.Wind.Gusts == *knots(23)
+		}},
+		{"KMH", "METAR UUEE 251800Z 24020KMH 9999 FEW030 12/05 Q1013", func(m types.METAR) bool {
+			return m.Wind.Speed == 11 && m.Wind.Gusts == nil
+		}},
+		{"whole and fraction", "METAR KXYZ 251756Z 27010KT 1 1/2SM BR OVC008 12/11 A2992", func(m types.METAR) bool {
+			return m.Visibility.Miles == 1.5 && !m.Visibility.Plus && !m.Visibility.Minus && m.WxString == "BR"
+		}},
+		{"fraction", "METAR KXYZ 251756Z 27010KT 3/4SM BR OVC008 12/11 A2992", func(m types.METAR) bool {
+			return m.Visibility.Miles == 0.75
+		}},
+		{"less than", "METAR KXYZ 251756Z 27010KT M1/4SM FG VV001 12/12 A2992", func(m types.METAR) bool {
+			return m.Visibility.Miles == 0.25 && m.Visibility.Minus
+		}},
+		{"more than", "METAR KXYZ 251756Z 27010KT P6SM SKC 12/05 A2992", func(m types.METAR) bool {
+			return m.Visibility.Miles == 6 && m.Visibility.Plus
+		}},
+		{"metric 9999", "METAR EGLL 251750Z 24008KT 9999 FEW030 12/05 Q1013", func(m types.METAR) bool {
+			return m.Visibility.Meters == 9999 && m.Visibility.Plus
+		}},
+		{"metric with directional minimum", "METAR EGLL 251750Z 24008KT 4000 1500SW BR BKN004 12/11 Q1013", func(m types.METAR) bool {
+			v := m.Visibility
+			return v.Meters == 4000 && *v.Directional == types.DirectionalVis{Meters: 1500, Direction: "SW"}
+		}},
+		{"RVR", "METAR KXYZ 251756Z 27010KT 1/4SM R28L/2400V4000FT/U R06/P6000FT R24/M0600FT/D R09/0800N FG VV002 12/12 A2992", func(m types.METAR) bool {
+			if len(m.RVR) != 4 {
+				return false
+			}
+			r := m.RVR
+			return r[0].Runway == "28L" && r[0].Feet == 2400 && *r[0].Max == 4000 && r[0].Trend == "U" &&
+				r[1].Runway == "06" && r[1].Feet == 6000 && r[1].Above && r[1].Max == nil &&
+				r[2].Feet == 600 && r[2].Below && r[2].Trend == "D" &&
+				r[3].Feet == 2625 && r[3].Trend == "N" // meters without FT
+		}},
+		{"vertical visibility", "METAR KXYZ 251756Z 27010KT 1/4SM FG VV002 12/12 A2992", func(m types.METAR) bool {
+			return m.VertVis != nil && *m.VertVis == *feet(200) && m.Clouds == nil
+		}},
+		{"cloud types", "METAR KXYZ 251756Z 27010KT 10SM FEW030CB BKN045TCU 12/05 A2992", func(m types.METAR) bool {
+			return len(m.Clouds) == 2 && m.Clouds[0] == types.CloudData{Base: 3000, Coverage: "few", Type: "CB"} &&
+				m.Clouds[1].Type == "TCU"
+		}},
+		{"Q altimeter", "METAR EGLL 251750Z 24008KT 9999 FEW030 12/05 Q1013", func(m types.METAR) bool {
+			return m.Altimeter > 29.91 && m.Altimeter < 29.92
+		}},
+		{"A before Q", "METAR KXYZ 251756Z 27010KT 10SM CLR 12/05 A2992 Q1013", func(m types.METAR) bool {
+			return m.Altimeter == 29.92
+		}},
+		{"Q before A", "METAR KXYZ 251756Z 27010KT 10SM CLR 12/05 Q1013 A2992", func(m types.METAR) bool {
+			return m.Altimeter == 29.92
+		}},
+		{"CAVOK", "METAR LFPG 251800Z 24010KT CAVOK 15/08 Q1020", func(m types.METAR) bool {
+			return m.Visibility.Plus && m.Clouds == nil && m.Temp.Ambient == 15 && m.Temp.Dewpoint == 8 && m.NotDecoded == ""
+		}},
+		{"CAVOK skips to temperature", "METAR LFPG 251800Z 24010KT CAVOK BKN030 15/08 Q1020", func(m types.METAR) bool {
+			return m.Clouds == nil && m.NotDecoded == "BKN030" && m.Temp.Ambient == 15
+		}},
+		{"slashed-out sensor", "METAR KXYZ 251756Z AUTO 27010KT 10SM BKN/// 12/05 A2992", func(m types.METAR) bool {
+			return m.NotDecoded == "BKN///" && m.Altimeter == 29.92
+		}},
+		{"SPECI, COR and AUTO", "SPECI KXYZ 251756Z COR AUTO 27010KT 10SM CLR M02/M05 A2992", func(m types.METAR) bool {
+			return m.Type == "SPECI" && m.Corrected && m.Auto && m.Temp.Ambient == -2 && m.Temp.Dewpoint == -5
+		}},
+	}
+	for _, tt := range tests {
+		metar, err := ParseMETAR(tt.raw, ref)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !tt.check(metar) {
+			t.Errorf("%s: %s\n%+v", tt.name, tt.raw, metar)
+		}
+	}
+}
+
+func TestParseMETARMalformed(t *testing.T) {
+	ref := time.Date(2025, 10, 25, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		raw   string
+		group string
+		token string
+	}{
+		{"METAR KXYZ 251756Z 2701XKT 10SM CLR 12/05 A2992", "wind", "2701XKT"},
+		{"METAR KXYZ 251756Z 37010KT 10SM CLR 12/05 A2992", "wind", "37010KT"},
+		{"METAR KXYZ 251756Z 27010KT 3/0SM CLR 12/05 A2992", "visibility", "3/0SM"},
+		{"METAR KXYZ 251756Z 27010KT 10SM BKN0X0 12/05 A2992", "sky", "BKN0X0"},
+		{"METAR KXYZ 251756Z 27010KT 1/4SM FG VV0X0 12/12 A2992", "sky", "VV0X0"},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR 123/45 A2992", "temperature", "123/45"},
+		{"METAR KXYZ 251756Z 27010KT 10SM CLR 12/05 A299", "altimeter", "A299"},
+		{"METAR KXYZ 251756Z 27010KT 1/4SM R28L/24X0FT FG 12/12 A2992", "RVR", "R28L/24X0FT"},
+		{"METAR KXYZ 259956Z 27010KT 10SM CLR 12/05 A2992", "observation time", "259956Z"},
+	}
+	for _, tt := range tests {
+		_, err := ParseMETAR(tt.raw, ref)
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) || tokenErr.Group != tt.group || tokenErr.Token != tt.token {
+			t.Errorf("%s: err = %v, want a %s error on %q", tt.raw, err, tt.group, tt.token)
+		}
+	}
+}
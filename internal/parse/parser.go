@@ -3,8 +3,8 @@ package parse
 import (
 	"fmt"
 	"log/slog"
+	"math"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
@@ -21,10 +21,8 @@ type ParseContext struct {
 type parseFunc func(c *ParseContext) error
 
 func BuildInternalMETAR(data *types.METARresponse, output *types.METAR) error {
-	output.Temp.AmbientExact = float64(data.Temp)
-	output.Temp.DewpointExact = float64(data.Dewp)
-	output.Temp.Ambient = int(data.Temp)
-	output.Temp.Dewpoint = int(data.Dewp)
+	output.Raw = data.RawOb
+	output.FltCat = data.FltCat
 	output.Reported.Age = int(time.Since(time.Unix(data.ObsTime, 0)).Minutes())
 
 	output.Clouds = make([]types.CloudData, 0)
@@ -39,10 +37,11 @@ func BuildInternalMETAR(data *types.METARresponse, output *types.METAR) error {
 	output.Reported.Local = provideTimeData(data.ReportTime, "local")
 
 	parsers := []parseFunc{
-		loadAltimeter, loadWind, loadWXString, loadRemarks,
+		loadBody, loadWXString, loadRemarks, loadTemps,
 	}
 	c := &ParseContext{
 		tokens: strings.Split(data.RawOb, " "),
+		input:  data,
 		output: output,
 	}
 
@@ -89,102 +88,151 @@ func provideCloudCover(coverage string) string {
 	}
 }
 
-// TODO: slices refactor?
-func loadAltimeter(ctx *ParseContext) error {
-	for _, token := range ctx.tokens {
-		if len(token) == 5 && strings.HasPrefix(token, "A") {
-			altVal, err := strconv.ParseFloat(token[1:], 64)
-			if err != nil {
-				return fmt.Errorf("getAltimeter failed: %w", err)
-			}
-			ctx.output.Altimeter = types.InHg(altVal / 100.0)
-			break
-		}
+// loadBody decodes the report body. If it won't decode, the API's own
+// decode is used instead so the rest of the report stays usable.
+func loadBody(ctx *ParseContext) error {
+	body, err := ParseMETAR(ctx.input.RawOb, time.Unix(ctx.input.ObsTime, 0))
+	if err != nil {
+		slog.Warn("METAR body decode failed, using API values", "error", err)
+		return loadBodyFromAPI(ctx)
 	}
+
+	out := ctx.output
+	out.Type = body.Type
+	out.Corrected = body.Corrected
+	out.Auto = body.Auto
+	out.Wind = body.Wind
+	out.Visibility = body.Visibility
+	out.RVR = body.RVR
+	out.WxString = body.WxString
+	out.Clouds = body.Clouds
+	out.VertVis = body.VertVis
+	out.Temp = body.Temp
+	out.Altimeter = body.Altimeter
+	out.NotDecoded = body.NotDecoded
 	return nil
 }
 
-// TODO: slices refactor?
-func loadWind(ctx *ParseContext) error {
-	for _, token := range ctx.tokens {
-		if strings.HasSuffix(token, "KT") {
-			if token[:3] == "VRB" {
-				ctx.output.Wind.Direction = types.DegMag(0)
-				ctx.output.Wind.Variable = true
-				speed, err := strconv.Atoi(token[3:5])
-				if err != nil {
-					return fmt.Errorf("getWind(0) failed: %w", err)
-				}
-				ctx.output.Wind.Speed = types.Knots(speed)
-			} else {
-				ctx.output.Wind.Variable = false
-				direction, err := strconv.Atoi(token[0:3])
-				if err != nil {
-					return fmt.Errorf("getWind(1) failed: %w", err)
-				}
-				ctx.output.Wind.Direction = types.DegMag(direction)
-				speed, err := strconv.Atoi(token[3:5])
-				if err != nil {
-					return fmt.Errorf("getWind(2) failed: %w", err)
-				}
-				ctx.output.Wind.Speed = types.Knots(speed)
-			}
-
-			if strings.Contains(token, "G") {
-				gustsIdx := strings.Index(token, "G")
-				gusts, err := strconv.Atoi(token[gustsIdx+1 : gustsIdx+3])
-				if err != nil {
-					return fmt.Errorf("getWind(3) failed: %w", err)
-				}
-				gustsValue := types.Knots(gusts)
-				ctx.output.Wind.Gusts = &gustsValue
-			} else {
-				ctx.output.Wind.Gusts = nil
-			}
-
-			if ctx.output.Wind.Speed == 0 && ctx.output.Wind.Direction == 0 {
-				ctx.output.Wind.Calm = true
-			} else {
-				ctx.output.Wind.Calm = false
-			}
+func loadBodyFromAPI(ctx *ParseContext) error {
+	data, out := ctx.input, ctx.output
+	out.Type = data.MetarType
+	out.WxString = data.WxString
+
+	if data.Wspd != nil {
+		var gust *int
+		if data.Wgst != nil {
+			knots := int(*data.Wgst)
+			gust = &knots
 		}
+		out.Wind = windFromAPI(data.Wdir, int(*data.Wspd), gust)
 	}
+	out.Visibility = data.Visib
+	// the API reports altimeter in hPa
+	out.Altimeter = types.InHg(data.Altim * inHgPerHPa)
 	return nil
 }
 
+// loadWXString decodes present weather from the body, or from the API's
+// wxString when the body wasn't decoded
 func loadWXString(ctx *ParseContext) error {
+	if ctx.output.WxString == "" {
+		ctx.output.WxString = ctx.input.WxString
+	}
+	weather, err := DecodeWeather(ctx.output.WxString)
+	if err != nil {
+		slog.Warn("weather decode incomplete", "error", err)
+	}
+	ctx.output.Weather = weather
 	return nil
 }
 
 func loadRemarks(ctx *ParseContext) error {
 	idx := slices.Index(ctx.tokens, "RMK")
-	if idx != -1 && idx+1 < len(ctx.tokens) {
-		rmkTokens := ctx.tokens[idx+1:]
-		raw, readable := processRemarks(rmkTokens)
-		ctx.output.Remarks.Raw = raw
-		ctx.output.Remarks.Readable = readable
+	if idx == -1 || idx+1 >= len(ctx.tokens) {
+		return nil
+	}
+	remarks, err := DecodeRemarks(ctx.tokens[idx+1:], time.Unix(ctx.input.ObsTime, 0))
+	if err != nil {
+		slog.Warn("remarks", "icao", ctx.input.IcaoID, "error", err)
+	}
+	ctx.output.Remarks = remarks
+	if vis := ctx.output.Visibility; vis != nil && remarks.VariableVis != nil {
+		vis.Variable = remarks.VariableVis
 	}
 	return nil
 }
 
-var matchCases = [2]string{"AO2", "$"}
-
-func processRemarks(remarkTokens []string) (raw []string, readable []string) {
-	// TODO: if token meets certain criteria, make it human-readable
-	// for now: arbitary creation of test strings
-	for _, token := range remarkTokens {
-		if slices.Contains(matchCases[:], token) {
-			slog.Debug("match", "token", token)
-			readable = append(readable, createReadableRemark(token))
-		} else {
-			slog.Debug("non-match", "token", token)
-			raw = append(raw, token)
+// loadTemps settles the exact temperature and dewpoint: the remarks T group
+// carries tenths, the body group whole degrees, and the API's floats are
+// only used when the report has neither. With none of the three the
+// temperature is marked missing rather than left at 0°C. Disagreement
+// between the API and the report is noted in DataWarnings.
+func loadTemps(ctx *ParseContext) error {
+	api, out, rmk := ctx.input, ctx.output, ctx.output.Remarks
+	fromBody := out.Temp.Source == "body"
+	// the body can carry a dewpoint even with the temperature slashed out
+	bodyDewpoint := !out.Temp.NoDewpoint && (fromBody || out.Temp.NoTemp)
+
+	switch {
+	case rmk.TempExact != nil:
+		if fromBody && math.Abs(*rmk.TempExact-float64(out.Temp.Ambient)) > 0.5 {
+			out.DataWarnings = append(out.DataWarnings, fmt.Sprintf(
+				"remarks temperature %.1f°C disagrees with body %d°C", *rmk.TempExact, out.Temp.Ambient))
+		}
+		out.Temp.AmbientExact = *rmk.TempExact
+		out.Temp.NoTemp = false
+		out.Temp.DewpointExact = float64(out.Temp.Dewpoint)
+		if rmk.DewpointExact != nil {
+			out.Temp.DewpointExact = *rmk.DewpointExact
+			out.Temp.NoDewpoint = false
+		} else if !bodyDewpoint {
+			out.Temp.NoDewpoint = true // a T group without its dewpoint half
+		}
+		if !fromBody {
+			out.Temp.Ambient = int(math.Round(out.Temp.AmbientExact))
+			out.Temp.Dewpoint = int(math.Round(out.Temp.DewpointExact))
 		}
+		out.Temp.Source = "remarks"
+	case fromBody:
+		out.Temp.AmbientExact = float64(out.Temp.Ambient)
+		out.Temp.DewpointExact = float64(out.Temp.Dewpoint)
+	default:
+		out.Temp = types.TempData{NoTemp: api.Temp == nil, NoDewpoint: api.Dewp == nil}
+		if api.Temp != nil {
+			out.Temp.Ambient = int(math.Round(*api.Temp))
+			out.Temp.AmbientExact = *api.Temp
+			out.Temp.Source = "api"
+		}
+		if api.Dewp != nil {
+			out.Temp.Dewpoint = int(math.Round(*api.Dewp))
+			out.Temp.DewpointExact = *api.Dewp
+		}
+		return nil
 	}
-	return raw, readable
-}
 
-func createReadableRemark(token string) string {
-	// TODO: need custom parsing. for now just output a string placedholder
-	return fmt.Sprintf("%s is a match", token)
+	tolerance := 0.05 // tenths from the T group
+	if out.Temp.Source == "body" {
+		tolerance = 0.5 // the body is rounded to whole degrees
+	}
+	type check struct {
+		name      string
+		api, want float64
+	}
+	var checks []check
+	if api.Temp != nil {
+		checks = append(checks, check{"temperature", *api.Temp, out.Temp.AmbientExact})
+	}
+	if api.Dewp != nil && !out.Temp.NoDewpoint {
+		checks = append(checks, check{"dewpoint", *api.Dewp, out.Temp.DewpointExact})
+	}
+	for _, c := range checks {
+		if math.Abs(c.api-c.want) > tolerance+1e-9 {
+			out.DataWarnings = append(out.DataWarnings, fmt.Sprintf(
+				"API %s %.1f°C, report says %.1f°C", c.name, c.api, c.want))
+		}
+	}
+	if len(out.DataWarnings) > 0 {
+		slog.Warn("METAR data quality", "icao", api.IcaoID, "warnings", out.DataWarnings)
+	}
+	return nil
 }
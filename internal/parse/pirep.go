@@ -0,0 +1,110 @@
+package parse
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// BuildInternalPIREP normalizes one report from the API, filling what the
+// API left undecoded from the raw text. Distance and bearing are left for
+// the caller, which knows the reference airport.
+func BuildInternalPIREP(data *types.PIREPresponse, output *types.PIREP) error {
+	output.Raw = data.RawOb
+	output.Urgent = data.PirepType == "Urgent PIREP" || strings.Contains(data.RawOb, " UUA ")
+	output.ObsTime = data.ObsTime
+	output.AcType = data.AcType
+	output.Lat = data.Lat
+	output.Lon = data.Long
+	output.AltitudeType = data.FltLvlType
+	output.WxString = data.WxString
+	if data.FltLvl != nil {
+		altitude := types.Feet(*data.FltLvl * 100)
+		output.Altitude = &altitude
+	}
+
+	output.Clouds = make([]types.PIREPCloud, 0, len(data.Clouds))
+	for _, layer := range data.Clouds {
+		output.Clouds = append(output.Clouds, types.PIREPCloud{
+			Coverage: provideCloudCover(layer.Cover),
+			Base:     pirepHeight(layer.Base),
+			Top:      pirepHeight(layer.Top),
+		})
+	}
+
+	output.Visibility = data.Visib
+	if data.Temp != nil {
+		temp := int(*data.Temp)
+		output.Temp = &temp
+	}
+	if data.Wspd != nil {
+		wind := types.WindData{Speed: types.Knots(*data.Wspd)}
+		if data.Wdir != nil {
+			wind.Direction = types.DegTrue(*data.Wdir)
+		}
+		output.Wind = &wind
+	}
+
+	output.Icing = appendPIREPLayer(nil, data.IcgInt1, data.IcgType1, "", data.IcgBas1, data.IcgTop1)
+	output.Icing = appendPIREPLayer(output.Icing, data.IcgInt2, data.IcgType2, "", data.IcgBas2, data.IcgTop2)
+	output.Turbulence = appendPIREPLayer(nil, data.TbInt1, data.TbType1, data.TbFreq1, data.TbBas1, data.TbTop1)
+	output.Turbulence = appendPIREPLayer(output.Turbulence, data.TbInt2, data.TbType2, data.TbFreq2, data.TbBas2, data.TbTop2)
+
+	raw, err := ParsePIREP(data.RawOb, time.Unix(data.ObsTime, 0))
+	if err != nil {
+		slog.Debug("raw PIREP decode failed", "error", err, "raw", data.RawOb)
+		return nil
+	}
+	FillPIREP(output, raw)
+	return nil
+}
+
+func appendPIREPLayer(layers []types.PIREPLayer, intensity, kind, freq string, base, top *int) []types.PIREPLayer {
+	if intensity == "" && kind == "" {
+		return layers
+	}
+	return append(layers, types.PIREPLayer{
+		Intensity: intensity,
+		Type:      kind,
+		Frequency: freq,
+		Base:      pirepHeight(base),
+		Top:       pirepHeight(top),
+	})
+}
+
+func pirepHeight(feet *int) *types.Feet {
+	if feet == nil || *feet == 0 {
+		return nil
+	}
+	height := types.Feet(*feet)
+	return &height
+}
+
+var pirepIntensityRank = map[string]int{
+	"NEG": 0, "TRC": 1, "TRACE": 1, "LGT": 2, "MOD": 3, "SEV": 4, "EXTM": 5, "EXTRM": 5,
+}
+
+// PeakIntensity reduces a reported intensity such as "LGT-MOD" to its
+// strongest part. NEG and unrecognized values report ok=false.
+func PeakIntensity(intensity string) (string, bool) {
+	peak, rank := "", 0
+	for part := range strings.SplitSeq(intensity, "-") {
+		if r := pirepIntensityRank[strings.TrimSpace(part)]; r > rank {
+			peak, rank = strings.TrimSpace(part), r
+		}
+	}
+	if peak == "TRACE" {
+		peak = "TRC"
+	}
+	if peak == "EXTRM" {
+		peak = "EXTM"
+	}
+	return peak, rank > 0
+}
+
+// PeakIntensityRank orders the values returned by PeakIntensity
+func PeakIntensityRank(intensity string) int {
+	return pirepIntensityRank[intensity]
+}
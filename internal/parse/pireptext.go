@@ -0,0 +1,349 @@
+package parse
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+var (
+	pirepFixRe    = regexp.MustCompile(`^([A-Z0-9]{2,5}?)(?:(\d{3})(\d{3}))?$`)
+	pirepTimeRe   = regexp.MustCompile(`^(\d{2})(\d{2})$`)
+	pirepLevelRe  = regexp.MustCompile(`^(\d{3})$`)
+	pirepSkyRe    = regexp.MustCompile(`^(FEW|SCT|BKN|OVC)(?:-(FEW|SCT|BKN|OVC))?(\d{3}|UNKN)?(?:-(?:TOPS?)?(\d{3}|UNKN))?$`)
+	pirepTopRe    = regexp.MustCompile(`^TOPS?(\d{3})?$`)
+	pirepBaseRe   = regexp.MustCompile(`^BASES?(\d{3})?$`)
+	pirepFlVisRe  = regexp.MustCompile(`^FV(\d{1,2})SM$`)
+	pirepTempRe   = regexp.MustCompile(`^([M-])?(\d{1,2})C?$`)
+	pirepWindRe   = regexp.MustCompile(`^(\d{3})(\d{2,3})(?:KT)?$`)
+	pirepRangeRe  = regexp.MustCompile(`^(\d{3})(?:-(\d{3}))?$`)
+	pirepIntensRe = regexp.MustCompile(`^(NEG|SMTH|TRC|TRACE|LGT|MOD|SEV|EXTM|EXTRM)(?:-(TRC|LGT|MOD|SEV|EXTM|EXTRM))?$`)
+)
+
+var (
+	pirepFrequencies = []string{"ISOL", "OCNL", "INTMT", "CONT"}
+	pirepTurbTypes   = []string{"CAT", "CHOP", "LLWS", "MWAVE"}
+	pirepIcingTypes  = []string{"RIME", "CLR", "MX", "MXD"}
+)
+
+// ParsePIREP decodes a raw UA/UUA report, slash-delimited after the
+// station and report type. ref is any time near the observation and
+// anchors /TM, which carries no date. Lat/Lon are left unset; the /OV fixes
+// are kept as reported.
+func ParsePIREP(raw string, ref time.Time) (types.PIREP, error) {
+	raw = strings.TrimSpace(raw)
+	output := types.PIREP{Raw: raw}
+
+	groups := strings.Split(raw, "/")
+	header := strings.Fields(groups[0])
+	switch {
+	case len(header) > 0 && header[len(header)-1] == "UUA":
+		output.Urgent = true
+	case len(header) > 0 && header[len(header)-1] == "UA":
+	default:
+		return types.PIREP{}, tokenErr("report type", groups[0], errMalformed)
+	}
+
+	for i := 1; i < len(groups); i++ {
+		tag, value, _ := strings.Cut(strings.TrimSpace(groups[i]), " ")
+		if len(tag) > 2 && tag != "RM" {
+			// "/FL020", "/TM1815" with no space
+			tag, value = tag[:2], tag[2:]+" "+value
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch tag {
+		case "OV":
+			output.Location, err = parsePIREPLocation(value)
+		case "TM":
+			output.ObsTime, err = parsePIREPTime(value, ref)
+		case "FL":
+			output.Altitude, output.AltitudeType, err = parsePIREPLevel(value)
+		case "TP":
+			output.AcType = value
+		case "SK":
+			output.Clouds, err = parsePIREPSky(value)
+		case "WX":
+			output.Visibility, output.WxString = parsePIREPWeather(value)
+		case "TA":
+			output.Temp, err = parsePIREPTemp(value)
+		case "WV":
+			output.Wind, err = parsePIREPWind(value)
+		case "TB":
+			output.Turbulence, err = parsePIREPLayers("turbulence", value, pirepTurbTypes)
+		case "IC":
+			output.Icing, err = parsePIREPLayers("icing", value, pirepIcingTypes)
+		case "RM":
+			// remarks run to the end and may contain slashes
+			output.Remarks = strings.TrimSpace(strings.TrimPrefix(strings.Join(groups[i:], "/"), "RM"))
+			i = len(groups)
+		}
+		if err != nil {
+			return types.PIREP{}, err
+		}
+	}
+
+	if output.AltitudeType == "" {
+		for _, token := range strings.Fields(output.Remarks) {
+			if token == "DURD" || token == "DURC" {
+				output.AltitudeType = token
+				break
+			}
+		}
+	}
+	return output, nil
+}
+
+// parsePIREPLocation reads a fix ("ANC"), a fix-radial-distance
+// ("TED255009") or a route between them ("ANC-ENA")
+func parsePIREPLocation(value string) ([]types.PIREPFix, error) {
+	var fixes []types.PIREPFix
+	for part := range strings.SplitSeq(strings.ReplaceAll(value, " ", ""), "-") {
+		m := pirepFixRe.FindStringSubmatch(part)
+		if m == nil {
+			return nil, tokenErr("location", value, errMalformed)
+		}
+		fix := types.PIREPFix{ID: m[1]}
+		if m[2] != "" {
+			radial := atoi(m[2])
+			if radial > 360 {
+				return nil, tokenErr("location", value, errRange)
+			}
+			r := types.DegMag(radial)
+			dist := atoi(m[3])
+			fix.Radial, fix.DistanceNM = &r, &dist
+		}
+		fixes = append(fixes, fix)
+	}
+	return fixes, nil
+}
+
+func parsePIREPTime(value string, ref time.Time) (int64, error) {
+	m := pirepTimeRe.FindStringSubmatch(value)
+	if m == nil {
+		return 0, tokenErr("time", value, errMalformed)
+	}
+	ref = ref.UTC()
+	obs, err := resolveDayTime(ref, ref.Day(), atoi(m[1]), atoi(m[2]))
+	if err != nil || atoi(m[1]) > 23 {
+		return 0, tokenErr("time", value, errRange)
+	}
+	// /TM has no date; take the occurrence nearest ref
+	switch {
+	case obs.Sub(ref) > 12*time.Hour:
+		obs = obs.AddDate(0, 0, -1)
+	case ref.Sub(obs) > 12*time.Hour:
+		obs = obs.AddDate(0, 0, 1)
+	}
+	return obs.Unix(), nil
+}
+
+// parsePIREPLevel reads "020", "UNKN", "DURD" or "DURC 020"
+func parsePIREPLevel(value string) (*types.Feet, string, error) {
+	var altitude *types.Feet
+	kind := ""
+	for token := range strings.FieldsSeq(value) {
+		switch {
+		case token == "UNKN":
+		case token == "DURD", token == "DURC":
+			kind = token
+		case pirepLevelRe.MatchString(token):
+			height, _ := hundredsOfFeet(token)
+			altitude = &height
+		default:
+			return nil, "", tokenErr("flight level", value, errMalformed)
+		}
+	}
+	return altitude, kind, nil
+}
+
+// parsePIREPSky reads layers such as "BKN030-TOP050", "OVC013",
+// "BKN-OVC030", "BASES 026" or "TOP046"; SKC/CLR is an empty slice
+func parsePIREPSky(value string) ([]types.PIREPCloud, error) {
+	layers := []types.PIREPCloud{}
+	tokens := strings.Fields(value)
+	height := func(s string) *types.Feet {
+		if s == "" || s == "UNKN" {
+			return nil
+		}
+		h, _ := hundredsOfFeet(s)
+		return &h
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		// "BASES 026" / "TOPS 046" put the height in the next token
+		if (token == "BASES" || token == "BASE" || token == "TOPS" || token == "TOP") &&
+			i+1 < len(tokens) && pirepLevelRe.MatchString(tokens[i+1]) {
+			token += tokens[i+1]
+			i++
+		}
+
+		switch m := pirepSkyRe.FindStringSubmatch(token); {
+		case token == "SKC" || token == "CLR":
+		case m != nil:
+			coverage := m[1]
+			if m[2] != "" {
+				coverage = m[1] + "-" + m[2]
+			}
+			layers = append(layers, types.PIREPCloud{
+				Coverage: provideCloudCover(coverage),
+				Base:     height(m[3]),
+				Top:      height(m[4]),
+			})
+		case pirepTopRe.MatchString(token):
+			top := height(pirepTopRe.FindStringSubmatch(token)[1])
+			if n := len(layers); n > 0 && layers[n-1].Top == nil {
+				layers[n-1].Top = top
+			} else {
+				layers = append(layers, types.PIREPCloud{Top: top})
+			}
+		case pirepBaseRe.MatchString(token):
+			layers = append(layers, types.PIREPCloud{Base: height(pirepBaseRe.FindStringSubmatch(token)[1])})
+		default:
+			return nil, tokenErr("sky", value, errMalformed)
+		}
+	}
+	return layers, nil
+}
+
+// parsePIREPWeather splits flight visibility ("FV05SM") from the weather
+func parsePIREPWeather(value string) (*types.Visibility, string) {
+	var vis *types.Visibility
+	var weather []string
+	for token := range strings.FieldsSeq(value) {
+		if m := pirepFlVisRe.FindStringSubmatch(token); m != nil {
+			miles := types.VisMiles(float64(atoi(m[1])))
+			vis = &miles
+			continue
+		}
+		weather = append(weather, token)
+	}
+	return vis, strings.Join(weather, " ")
+}
+
+func parsePIREPTemp(value string) (*int, error) {
+	m := pirepTempRe.FindStringSubmatch(strings.ReplaceAll(value, " ", ""))
+	if m == nil {
+		return nil, tokenErr("temperature", value, errMalformed)
+	}
+	temp := atoi(m[2])
+	if m[1] != "" {
+		temp = -temp
+	}
+	return &temp, nil
+}
+
+func parsePIREPWind(value string) (*types.WindData, error) {
+	m := pirepWindRe.FindStringSubmatch(strings.ReplaceAll(value, " ", ""))
+	if m == nil {
+		return nil, tokenErr("wind", value, errMalformed)
+	}
+	dir := atoi(m[1])
+	if dir > 360 {
+		return nil, tokenErr("wind", value, errRange)
+	}
+	return &types.WindData{Direction: types.DegTrue(dir), Speed: types.Knots(atoi(m[2]))}, nil
+}
+
+// parsePIREPLayers reads /TB or /IC: layers separated by ";" or ",", each
+// an intensity (or range) with optional frequency, type and altitudes
+// ("050-080", "ABV 180", "BLO 050")
+func parsePIREPLayers(group, value string, kinds []string) ([]types.PIREPLayer, error) {
+	var layers []types.PIREPLayer
+	for part := range strings.FieldsFuncSeq(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		var layer types.PIREPLayer
+		tokens := strings.Fields(part)
+		for i := 0; i < len(tokens); i++ {
+			token := tokens[i]
+			switch {
+			case pirepIntensRe.MatchString(token):
+				layer.Intensity = token
+			case slices.Contains(pirepFrequencies, token):
+				layer.Frequency = token
+			case slices.Contains(kinds, token):
+				layer.Type = token
+			case token == "ABV" && i+1 < len(tokens):
+				base, err := hundredsOfFeet(tokens[i+1])
+				if err != nil {
+					return nil, tokenErr(group, part, err)
+				}
+				layer.Base = &base
+				i++
+			case token == "BLO" && i+1 < len(tokens):
+				top, err := hundredsOfFeet(tokens[i+1])
+				if err != nil {
+					return nil, tokenErr(group, part, err)
+				}
+				layer.Top = &top
+				i++
+			case pirepRangeRe.MatchString(token):
+				m := pirepRangeRe.FindStringSubmatch(token)
+				base, _ := hundredsOfFeet(m[1])
+				layer.Base = &base
+				if m[2] != "" {
+					top, _ := hundredsOfFeet(m[2])
+					layer.Top = &top
+				}
+			case token == "DURC" || token == "DURD":
+			default:
+				return nil, tokenErr(group, part, errMalformed)
+			}
+		}
+		if layer.Intensity == "" {
+			return nil, tokenErr(group, part, errors.New("missing intensity"))
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// FillPIREP copies elements decoded from the raw text into the places the
+// API left empty
+func FillPIREP(output *types.PIREP, raw types.PIREP) {
+	output.Urgent = output.Urgent || raw.Urgent
+	if len(output.Location) == 0 {
+		output.Location = raw.Location
+	}
+	if output.ObsTime == 0 {
+		output.ObsTime = raw.ObsTime
+	}
+	if output.Altitude == nil {
+		output.Altitude = raw.Altitude
+	}
+	if output.AltitudeType == "" {
+		output.AltitudeType = raw.AltitudeType
+	}
+	if output.AcType == "" {
+		output.AcType = raw.AcType
+	}
+	if len(output.Clouds) == 0 {
+		output.Clouds = raw.Clouds
+	}
+	if output.Visibility == nil {
+		output.Visibility = raw.Visibility
+	}
+	if output.WxString == "" {
+		output.WxString = raw.WxString
+	}
+	if output.Temp == nil {
+		output.Temp = raw.Temp
+	}
+	if output.Wind == nil {
+		output.Wind = raw.Wind
+	}
+	if len(output.Icing) == 0 {
+		output.Icing = raw.Icing
+	}
+	if len(output.Turbulence) == 0 {
+		output.Turbulence = raw.Turbulence
+	}
+	if output.Remarks == "" {
+		output.Remarks = raw.Remarks
+	}
+}
@@ -0,0 +1,114 @@
+package parse
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func TestParsePIREP(t *testing.T) {
+	ref := time.Date(2025, 10, 25, 18, 30, 0, 0, time.UTC)
+	tm := time.Date(2025, 10, 25, 18, 15, 0, 0, time.UTC).Unix()
+	feet := func(f types.Feet) *types.Feet { return &f }
+
+	tests := []struct {
+		raw  string
+		want types.PIREP
+	}{
+		{"SGF UA /OV SGF/TM 1815/FL050/TP C172/SK BKN030-TOP045/TA M02/WV 27025KT/TB LGT-MOD 040-060/IC LGT RIME 030-050",
+			types.PIREP{
+				Location: []types.PIREPFix{{ID: "SGF"}},
+				ObsTime:  tm,
+				Altitude: feet(5000),
+				AcType:   "C172",
+				Clouds:   []types.PIREPCloud{{Coverage: "broken", Base: feet(3000), Top: feet(4500)}},
+				Temp:     ptr(-2),
+				Wind:     &types.WindData{Direction: 270, Speed: 25},
+				Turbulence: []types.PIREPLayer{
+					{Intensity: "LGT-MOD", Base: feet(4000), Top: feet(6000)},
+				},
+				Icing: []types.PIREPLayer{
+					{Intensity: "LGT", Type: "RIME", Base: feet(3000), Top: feet(5000)},
+				},
+			}},
+		{"JLN UUA /OV SGF090020/TM 1815/FL UNKN/TP B737/TB SEV CAT ABV 180",
+			types.PIREP{
+				Urgent:   true,
+				Location: []types.PIREPFix{{ID: "SGF", Radial: ptr(types.DegMag(90)), DistanceNM: ptr(20)}},
+				ObsTime:  tm,
+				AcType:   "B737",
+				Turbulence: []types.PIREPLayer{
+					{Intensity: "SEV", Type: "CAT", Base: feet(18000)},
+				},
+			}},
+		{"SGF UA /OV SGF-JLN/TM 1815/FL DURC/TP PA28/SK BKN-OVC030 TOPS 060/WX FV05SM -RA/IC TRC-LGT MX BLO 080; MOD CLR 090-110",
+			types.PIREP{
+				Location:     []types.PIREPFix{{ID: "SGF"}, {ID: "JLN"}},
+				ObsTime:      tm,
+				AltitudeType: "DURC",
+				AcType:       "PA28",
+				Clouds:       []types.PIREPCloud{{Coverage: "BKN-OVC", Base: feet(3000), Top: feet(6000)}},
+				Visibility:   ptr(types.VisMiles(5)),
+				WxString:     "-RA",
+				Icing: []types.PIREPLayer{
+					{Intensity: "TRC-LGT", Type: "MX", Top: feet(8000)},
+					{Intensity: "MOD", Type: "CLR", Base: feet(9000), Top: feet(11000)},
+				},
+			}},
+		{"BBG UA /OV BBG/TM 0010/FL DURD 020/TP C208/SK SKC/TB NEG/RM SMOOTH/CALM",
+			types.PIREP{
+				Location:     []types.PIREPFix{{ID: "BBG"}},
+				ObsTime:      time.Date(2025, 10, 26, 0, 10, 0, 0, time.UTC).Unix(), // nearest ref is tomorrow
+				Altitude:     feet(2000),
+				AltitudeType: "DURD",
+				AcType:       "C208",
+				Clouds:       []types.PIREPCloud{},
+				Turbulence:   []types.PIREPLayer{{Intensity: "NEG"}},
+				Remarks:      "SMOOTH/CALM",
+			}},
+		{"SGF UA /OV SGF/TM 1815/FLUNKN/TP CRJ9/RM DURC",
+			types.PIREP{
+				Location:     []types.PIREPFix{{ID: "SGF"}},
+				ObsTime:      tm,
+				AltitudeType: "DURC",
+				AcType:       "CRJ9",
+				Remarks:      "DURC",
+			}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePIREP(tt.raw, ref)
+		if err != nil {
+			t.Errorf("%s: %v", tt.raw, err)
+			continue
+		}
+		tt.want.Raw = tt.raw
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParsePIREPMalformed(t *testing.T) {
+	ref := time.Date(2025, 10, 25, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		raw, group string
+	}{
+		{"SGF PIREP /OV SGF", "report type"},
+		{"SGF UA /OV SGF400010/TM 1815", "location"},
+		{"SGF UA /OV SGF/TM 2515", "time"},
+		{"SGF UA /OV SGF/FL HIGH", "flight level"},
+		{"SGF UA /OV SGF/SK LOTS", "sky"},
+		{"SGF UA /OV SGF/TB CAT 040-060", "turbulence"},
+		{"SGF UA /OV SGF/IC MOD RIME ABV XYZ", "icing"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePIREP(tt.raw, ref)
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) || tokenErr.Group != tt.group {
+			t.Errorf("%s: err = %v, want a %s error", tt.raw, err, tt.group)
+		}
+	}
+}
@@ -1 +1,555 @@
 package parse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+var (
+	rmkPeakWindRe  = regexp.MustCompile(`^(\d{3})(\d{2,3})/(\d{2})?(\d{2})$`)
+	rmkTimeRe      = regexp.MustCompile(`^(\d{2})?(\d{2})$`)
+	rmkWxEventsRe  = regexp.MustCompile(`^(?:[A-Z+-]{2,7}(?:[BE]\d{2}(?:\d{2})?)+)+$`)
+	rmkWxEventRe   = regexp.MustCompile(`([A-Z+-]{2,7}?)((?:[BE]\d{2}(?:\d{2})?)+)`)
+	rmkEventTimeRe = regexp.MustCompile(`([BE])(\d{2}(?:\d{2})?)`)
+	rmkVarCigRe    = regexp.MustCompile(`^(\d{3})V(\d{3})$`)
+	rmkVarVisRe    = regexp.MustCompile(`^VIS (\d+(?: \d/\d{1,2})?|\d/\d{1,2})V(\d+(?: \d/\d{1,2})?|\d/\d{1,2})$`)
+	rmkSLPRe       = regexp.MustCompile(`^SLP(\d{3})$`)
+	rmkPrecipRe    = regexp.MustCompile(`^(P|6|7)(\d{4}|////)$`)
+	rmkExactTempRe = regexp.MustCompile(`^T([01])(\d{3})(?:([01])(\d{3}))?$`)
+	rmk6hTempRe    = regexp.MustCompile(`^([12])([01])(\d{3})$`)
+	rmk24hTempRe   = regexp.MustCompile(`^4([01])(\d{3})([01])(\d{3})$`)
+	rmkTendencyRe  = regexp.MustCompile(`^5([0-8])(\d{3})$`)
+	rmkLightningRe = regexp.MustCompile(`^LTG((?:IC|CC|CG|CA)*)$`)
+	rmkDirectionRe = regexp.MustCompile(`^(?:N|NE|E|SE|S|SW|W|NW)(?:-(?:N|NE|E|SE|S|SW|W|NW))*$`)
+	rmkRunwayRe    = regexp.MustCompile(`^RWY\d{2}[LCR]?$`)
+)
+
+var stationTypes = map[string]string{
+	"AO1": "automated station without precipitation discriminator",
+	"AO2": "automated station with precipitation discriminator",
+}
+
+// sensorOutages are the "not available" remarks, some of which may be
+// followed by the runway the sensor serves
+var sensorOutages = map[string]string{
+	"RVRNO":  "RVR not available",
+	"PWINO":  "precipitation identifier not available",
+	"PNO":    "precipitation amount not available",
+	"FZRANO": "freezing rain information not available",
+	"TSNO":   "thunderstorm information not available",
+	"SLPNO":  "sea-level pressure not available",
+	"VISNO":  "secondary visibility not available",
+	"CHINO":  "secondary ceiling not available",
+}
+
+var lightningFrequencies = map[string]string{
+	"OCNL": "occasional",
+	"FRQ":  "frequent",
+	"CONS": "continuous",
+}
+
+var lightningTypes = map[string]string{
+	"IC": "in-cloud",
+	"CC": "cloud-to-cloud",
+	"CG": "cloud-to-ground",
+	"CA": "cloud-to-air",
+}
+
+var lightningLocations = map[string]string{
+	"DSNT":  "distant",
+	"VC":    "in the vicinity",
+	"OHD":   "overhead",
+	"ALQDS": "all quadrants",
+	"AND":   "and",
+}
+
+// pressureTendencies describes the 5appp characteristic, WMO code table 0200
+var pressureTendencies = [9]string{
+	"rising then falling",
+	"rising then steady",
+	"rising",
+	"falling or steady then rising",
+	"steady",
+	"falling then rising",
+	"falling then steady",
+	"falling",
+	"steady or rising then falling",
+}
+
+// remarkState collects one report's remarks; obs anchors the hour- and
+// minute-only times of PK WND, WSHFT and begin/end groups
+type remarkState struct {
+	obs    time.Time
+	output *types.Remarks
+}
+
+func (r *remarkState) say(format string, args ...any) {
+	r.output.Readable = append(r.output.Readable, fmt.Sprintf(format, args...))
+}
+
+// remarkDecoder tries one remark group at tokens[i], returning how many
+// tokens it used (0 when the group isn't there)
+type remarkDecoder func(r *remarkState, tokens []string, i int) (int, error)
+
+var remarkDecoders = []remarkDecoder{
+	decodeStationType,
+	decodePeakWind,
+	decodeWindShift,
+	decodeWeatherEvents,
+	decodeVariableCeiling,
+	decodeVariableVis,
+	decodeSeaLevelPressure,
+	decodePrecip,
+	decodeExactTemp,
+	decode6hTemp,
+	decode24hTemp,
+	decodePressureTendency,
+	decodeLightning,
+	decodeSensorOutage,
+	decodeMaintenance,
+}
+
+// DecodeRemarks decodes the tokens after RMK in a US METAR. Groups that
+// aren't recognised are kept in Raw; ones that look right but don't decode
+// are also reported in err.
+func DecodeRemarks(tokens []string, obs time.Time) (types.Remarks, error) {
+	output := types.Remarks{Raw: []string{}, Readable: []string{}}
+	r := &remarkState{obs: obs.UTC(), output: &output}
+
+	var errs []error
+	for i := 0; i < len(tokens); {
+		used := 0
+		for _, decode := range remarkDecoders {
+			n, err := decode(r, tokens, i)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+			if n > 0 {
+				used = n
+				break
+			}
+		}
+		if used == 0 {
+			output.Raw = append(output.Raw, tokens[i])
+			used = 1
+		}
+		i += used
+	}
+	return output, errors.Join(errs...)
+}
+
+func decodeStationType(r *remarkState, tokens []string, i int) (int, error) {
+	text, ok := stationTypes[tokens[i]]
+	if !ok {
+		return 0, nil
+	}
+	r.output.Station = tokens[i]
+	r.say("%s", text)
+	return 1, nil
+}
+
+// decodePeakWind reads PK WND dddff(f)/(hh)mm
+func decodePeakWind(r *remarkState, tokens []string, i int) (int, error) {
+	if tokens[i] != "PK" || i+2 >= len(tokens) || tokens[i+1] != "WND" {
+		return 0, nil
+	}
+	m := rmkPeakWindRe.FindStringSubmatch(tokens[i+2])
+	if m == nil {
+		return 0, tokenErr("peak wind", tokens[i+2], errMalformed)
+	}
+	dir, _ := strconv.Atoi(m[1])
+	speed, _ := strconv.Atoi(m[2])
+	at, err := remarkTime(m[3], m[4], r.obs)
+	if err != nil || dir > 360 {
+		return 0, tokenErr("peak wind", tokens[i+2], errRange)
+	}
+	r.output.PeakWind = &types.PeakWind{
+		Direction: types.DegTrue(dir),
+		Speed:     types.Knots(speed),
+		Time:      at.Unix(),
+	}
+	r.say("peak wind %03d° at %d kt at %s", dir, speed, at.Format("1504Z"))
+	return 3, nil
+}
+
+// decodeWindShift reads WSHFT (hh)mm [FROPA]
+func decodeWindShift(r *remarkState, tokens []string, i int) (int, error) {
+	if tokens[i] != "WSHFT" || i+1 >= len(tokens) {
+		return 0, nil
+	}
+	m := rmkTimeRe.FindStringSubmatch(tokens[i+1])
+	if m == nil {
+		return 0, tokenErr("wind shift", tokens[i+1], errMalformed)
+	}
+	at, err := remarkTime(m[1], m[2], r.obs)
+	if err != nil {
+		return 0, tokenErr("wind shift", tokens[i+1], err)
+	}
+
+	shift := &types.WindShift{Time: at.Unix()}
+	used := 2
+	if i+2 < len(tokens) && tokens[i+2] == "FROPA" {
+		shift.Frontal = true
+		used++
+	}
+	r.output.WindShift = shift
+	if shift.Frontal {
+		r.say("wind shift at %s with frontal passage", at.Format("1504Z"))
+	} else {
+		r.say("wind shift at %s", at.Format("1504Z"))
+	}
+	return used, nil
+}
+
+// decodeWeatherEvents reads begin/end groups such as RAB1657E07B48 or
+// TSB12RAB12E30, one or more weather types each with its own times
+func decodeWeatherEvents(r *remarkState, tokens []string, i int) (int, error) {
+	token := tokens[i]
+	if !rmkWxEventsRe.MatchString(token) {
+		return 0, nil
+	}
+	groups := rmkWxEventRe.FindAllStringSubmatch(token, -1)
+	for _, g := range groups {
+		if !isWeatherToken(g[1]) {
+			return 0, nil
+		}
+	}
+
+	for _, g := range groups {
+		wx, err := decodeWeatherGroup(g[1])
+		if err != nil {
+			return 0, err
+		}
+		var times []string
+		for _, t := range rmkEventTimeRe.FindAllStringSubmatch(g[2], -1) {
+			hour, minute := "", t[2]
+			if len(t[2]) == 4 {
+				hour, minute = t[2][:2], t[2][2:]
+			}
+			at, err := remarkTime(hour, minute, r.obs)
+			if err != nil {
+				return 0, tokenErr("weather begin/end", token, err)
+			}
+			began := t[1] == "B"
+			r.output.WeatherEvents = append(r.output.WeatherEvents, types.WeatherEvent{
+				Weather: g[1],
+				Began:   began,
+				Time:    at.Unix(),
+			})
+			verb := "ended"
+			if began {
+				verb = "began"
+			}
+			times = append(times, verb+" "+at.Format("1504Z"))
+		}
+		r.say("%s %s", wx.Text, strings.Join(times, ", "))
+	}
+	return 1, nil
+}
+
+// decodeVariableCeiling reads CIG hhhVhhh
+func decodeVariableCeiling(r *remarkState, tokens []string, i int) (int, error) {
+	if tokens[i] != "CIG" || i+1 >= len(tokens) {
+		return 0, nil
+	}
+	m := rmkVarCigRe.FindStringSubmatch(tokens[i+1])
+	if m == nil {
+		return 0, nil // e.g. a second-site ceiling, CIG 002 RWY11
+	}
+	low, _ := hundredsOfFeet(m[1])
+	high, _ := hundredsOfFeet(m[2])
+	r.output.VariableCeiling = &types.VariableCeiling{Min: low, Max: high}
+	r.say("ceiling variable %d-%d ft", low, high)
+	return 2, nil
+}
+
+// decodeVariableVis reads VIS nVn, where either side may be a fraction or
+// a whole number and fraction, e.g. VIS 3/4V1 1/2
+func decodeVariableVis(r *remarkState, tokens []string, i int) (int, error) {
+	if tokens[i] != "VIS" {
+		return 0, nil
+	}
+	for n := 3; n >= 2; n-- {
+		if i+n > len(tokens) {
+			continue
+		}
+		m := rmkVarVisRe.FindStringSubmatch(strings.Join(tokens[i:i+n], " "))
+		if m == nil {
+			continue
+		}
+		low, err := remarkMiles(m[1])
+		if err != nil {
+			return 0, tokenErr("variable visibility", m[0], err)
+		}
+		high, err := remarkMiles(m[2])
+		if err != nil {
+			return 0, tokenErr("variable visibility", m[0], err)
+		}
+		r.output.VariableVis = &types.VariableVis{Min: low, Max: high}
+		r.say("visibility variable %s-%s SM", m[1], m[2])
+		return n, nil
+	}
+	return 0, nil
+}
+
+// decodeSeaLevelPressure reads SLPppp, tenths of hPa without the leading 9 or 10
+func decodeSeaLevelPressure(r *remarkState, tokens []string, i int) (int, error) {
+	m := rmkSLPRe.FindStringSubmatch(tokens[i])
+	if m == nil {
+		return 0, nil
+	}
+	tenths, _ := strconv.Atoi(m[1])
+	hPa := float64(tenths) / 10
+	if tenths < 500 {
+		hPa += 1000
+	} else {
+		hPa += 900
+	}
+	r.output.SeaLevelPressure = &hPa
+	r.say("sea-level pressure %.1f hPa", hPa)
+	return 1, nil
+}
+
+// decodePrecip reads the Prrrr hourly, 6rrrr 3- or 6-hourly and 7rrrr
+// 24-hour amounts in hundredths of an inch; 0000 is a trace and //// means
+// precipitation fell but wasn't measured
+func decodePrecip(r *remarkState, tokens []string, i int) (int, error) {
+	m := rmkPrecipRe.FindStringSubmatch(tokens[i])
+	if m == nil {
+		return 0, nil
+	}
+
+	var hours int
+	var target **types.Precip
+	switch m[1] {
+	case "P":
+		hours, target = 1, &r.output.PrecipHourly
+	case "6":
+		// the 6-group covers 6 hours in the synoptic reports, 3 otherwise
+		hours, target = 3, &r.output.Precip3or6h
+		if r.obs.Add(30*time.Minute).Hour()%6 == 0 {
+			hours = 6
+		}
+	case "7":
+		hours, target = 24, &r.output.Precip24h
+	}
+
+	if m[2] == "////" {
+		r.say("%d-hour precipitation indeterminate", hours)
+		return 1, nil
+	}
+	hundredths, _ := strconv.Atoi(m[2])
+	precip := &types.Precip{Inches: float64(hundredths) / 100, Trace: hundredths == 0, Hours: hours}
+	*target = precip
+	if precip.Trace {
+		r.say("%d-hour precipitation trace", hours)
+	} else {
+		r.say("%d-hour precipitation %.2f in", hours, precip.Inches)
+	}
+	return 1, nil
+}
+
+// decodeExactTemp reads TsTTTsDDD, temperature and dewpoint in tenths
+func decodeExactTemp(r *remarkState, tokens []string, i int) (int, error) {
+	m := rmkExactTempRe.FindStringSubmatch(tokens[i])
+	if m == nil {
+		return 0, nil
+	}
+	temp := tenthsCelsius(m[1], m[2])
+	r.output.TempExact = &temp
+	if m[3] == "" {
+		r.say("temperature %.1f°C", temp)
+		return 1, nil
+	}
+	dew := tenthsCelsius(m[3], m[4])
+	r.output.DewpointExact = &dew
+	r.say("temperature %.1f°C, dewpoint %.1f°C", temp, dew)
+	return 1, nil
+}
+
+// decode6hTemp reads the 1sTTT maximum and 2sTTT minimum of the last 6 hours
+func decode6hTemp(r *remarkState, tokens []string, i int) (int, error) {
+	m := rmk6hTempRe.FindStringSubmatch(tokens[i])
+	if m == nil {
+		return 0, nil
+	}
+	temp := tenthsCelsius(m[2], m[3])
+	if m[1] == "1" {
+		r.output.Max6h = &temp
+		r.say("6-hour max temperature %.1f°C", temp)
+	} else {
+		r.output.Min6h = &temp
+		r.say("6-hour min temperature %.1f°C", temp)
+	}
+	return 1, nil
+}
+
+// decode24hTemp reads 4sTTTsTTT, the 24-hour maximum and minimum
+func decode24hTemp(r *remarkState, tokens []string, i int) (int, error) {
+	m := rmk24hTempRe.FindStringSubmatch(tokens[i])
+	if m == nil {
+		return 0, nil
+	}
+	high := tenthsCelsius(m[1], m[2])
+	low := tenthsCelsius(m[3], m[4])
+	r.output.Max24h, r.output.Min24h = &high, &low
+	r.say("24-hour max/min temperature %.1f°C / %.1f°C", high, low)
+	return 1, nil
+}
+
+// decodePressureTendency reads 5appp: the characteristic a and the 3-hour
+// change in tenths of hPa, whose sign follows from a
+func decodePressureTendency(r *remarkState, tokens []string, i int) (int, error) {
+	m := rmkTendencyRe.FindStringSubmatch(tokens[i])
+	if m == nil {
+		return 0, nil
+	}
+	code, _ := strconv.Atoi(m[1])
+	tenths, _ := strconv.Atoi(m[2])
+	change := float64(tenths) / 10
+	if code > 4 {
+		change = -change
+	}
+	r.output.PressureTendency = &types.PressureTendency{Code: code, Change: change}
+	r.say("3-hour pressure change %+.1f hPa, %s", change, pressureTendencies[code])
+	return 1, nil
+}
+
+// decodeLightning reads [OCNL|FRQ|CONS] LTG[IC][CC][CG][CA] followed by
+// where it was, e.g. FRQ LTGICCG DSNT NE-SE AND OHD
+func decodeLightning(r *remarkState, tokens []string, i int) (int, error) {
+	j := i
+	freq := ""
+	if _, ok := lightningFrequencies[tokens[j]]; ok {
+		freq = tokens[j]
+		j++
+	}
+	if j >= len(tokens) {
+		return 0, nil
+	}
+	m := rmkLightningRe.FindStringSubmatch(tokens[j])
+	if m == nil {
+		return 0, nil
+	}
+	j++
+
+	ltg := types.Lightning{Frequency: freq, Types: []string{}}
+	var typeNames []string
+	for code := range weatherCodes(m[1]) {
+		ltg.Types = append(ltg.Types, code)
+		typeNames = append(typeNames, lightningTypes[code])
+	}
+
+	var where, whereText []string
+	for ; j < len(tokens); j++ {
+		token := tokens[j]
+		if name, ok := lightningLocations[token]; ok {
+			where, whereText = append(where, token), append(whereText, name)
+			continue
+		}
+		if rmkDirectionRe.MatchString(token) {
+			where, whereText = append(where, token), append(whereText, token)
+			continue
+		}
+		break
+	}
+	// a trailing AND belongs to whatever comes next
+	for len(where) > 0 && where[len(where)-1] == "AND" {
+		where, whereText = where[:len(where)-1], whereText[:len(whereText)-1]
+		j--
+	}
+	ltg.Location = strings.Join(where, " ")
+	r.output.Lightning = append(r.output.Lightning, ltg)
+
+	text := "lightning"
+	if freq != "" {
+		text = lightningFrequencies[freq] + " " + text
+	}
+	if len(typeNames) > 0 {
+		text += " " + strings.Join(typeNames, " and ")
+	}
+	if len(whereText) > 0 {
+		text += " " + strings.Join(whereText, " ")
+	}
+	r.say("%s", text)
+	return j - i, nil
+}
+
+func decodeSensorOutage(r *remarkState, tokens []string, i int) (int, error) {
+	text, ok := sensorOutages[tokens[i]]
+	if !ok {
+		return 0, nil
+	}
+	if !slices.Contains(r.output.SensorOutages, tokens[i]) {
+		r.output.SensorOutages = append(r.output.SensorOutages, tokens[i])
+	}
+	if i+1 < len(tokens) && rmkRunwayRe.MatchString(tokens[i+1]) {
+		r.say("%s at %s", text, tokens[i+1])
+		return 2, nil
+	}
+	r.say("%s", text)
+	return 1, nil
+}
+
+func decodeMaintenance(r *remarkState, tokens []string, i int) (int, error) {
+	if tokens[i] != "$" {
+		return 0, nil
+	}
+	r.output.Maintenance = true
+	r.say("station needs maintenance")
+	return 1, nil
+}
+
+// remarkTime places an (hh)mm remark time at or before obs; without an
+// hour it falls in the observation's hour
+func remarkTime(hour, minute string, obs time.Time) (time.Time, error) {
+	h, m := obs.Hour(), atoi(minute)
+	if hour != "" {
+		h = atoi(hour)
+	}
+	if h > 23 || m > 59 {
+		return time.Time{}, errRange
+	}
+	at := time.Date(obs.Year(), obs.Month(), obs.Day(), h, m, 0, 0, time.UTC)
+	if at.After(obs) {
+		if hour == "" {
+			at = at.Add(-time.Hour)
+		} else {
+			at = at.AddDate(0, 0, -1)
+		}
+	}
+	return at, nil
+}
+
+// remarkMiles reads "2", "1/2" or "1 1/2"
+func remarkMiles(s string) (float64, error) {
+	whole, frac, hasFrac := strings.Cut(s, "/")
+	if !hasFrac {
+		return float64(atoi(s)), nil
+	}
+	miles := 0.0
+	if w, num, ok := strings.Cut(whole, " "); ok {
+		miles, whole = float64(atoi(w)), num
+	}
+	f, err := fraction(whole, frac)
+	if err != nil {
+		return 0, err
+	}
+	return miles + f, nil
+}
+
+func tenthsCelsius(sign, digits string) float64 {
+	v := float64(atoi(digits)) / 10
+	if sign == "1" {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package parse
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestDecodeRemarks(t *testing.T) {
+	synoptic := time.Date(2025, 10, 25, 17, 53, 0, 0, time.UTC)
+	intermediate := time.Date(2025, 10, 25, 14, 53, 0, 0, time.UTC)
+
+	tests := []struct {
+		remarks  string
+		obs      time.Time
+		want     types.Remarks
+		readable string
+	}{
+		{"T01560083", synoptic, types.Remarks{TempExact: ptr(15.6), DewpointExact: ptr(8.3)},
+			"temperature 15.6°C, dewpoint 8.3°C"},
+		{"T10221050", synoptic, types.Remarks{TempExact: ptr(-2.2), DewpointExact: ptr(-5.0)},
+			"temperature -2.2°C, dewpoint -5.0°C"},
+		{"T0156", synoptic, types.Remarks{TempExact: ptr(15.6)}, "temperature 15.6°C"},
+		{"SLP132", synoptic, types.Remarks{SeaLevelPressure: ptr(1013.2)}, "sea-level pressure 1013.2 hPa"},
+		{"SLP982", synoptic, types.Remarks{SeaLevelPressure: ptr(998.2)}, "sea-level pressure 998.2 hPa"},
+		{"52015", synoptic, types.Remarks{PressureTendency: &types.PressureTendency{Code: 2, Change: 1.5}},
+			"3-hour pressure change +1.5 hPa, rising"},
+		{"57008", synoptic, types.Remarks{PressureTendency: &types.PressureTendency{Code: 7, Change: -0.8}},
+			"3-hour pressure change -0.8 hPa, falling"},
+		{"10178", synoptic, types.Remarks{Max6h: ptr(17.8)}, "6-hour max temperature 17.8°C"},
+		{"21006", synoptic, types.Remarks{Min6h: ptr(-0.6)}, "6-hour min temperature -0.6°C"},
+		{"401781006", synoptic, types.Remarks{Max24h: ptr(17.8), Min24h: ptr(-0.6)},
+			"24-hour max/min temperature 17.8°C / -0.6°C"},
+		{"P0012", synoptic, types.Remarks{PrecipHourly: &types.Precip{Inches: 0.12, Hours: 1}},
+			"1-hour precipitation 0.12 in"},
+		{"P0000", synoptic, types.Remarks{PrecipHourly: &types.Precip{Trace: true, Hours: 1}},
+			"1-hour precipitation trace"},
+		{"60105", synoptic, types.Remarks{Precip3or6h: &types.Precip{Inches: 1.05, Hours: 6}},
+			"6-hour precipitation 1.05 in"},
+		{"60105", intermediate, types.Remarks{Precip3or6h: &types.Precip{Inches: 1.05, Hours: 3}},
+			"3-hour precipitation 1.05 in"},
+		{"6////", synoptic, types.Remarks{}, "6-hour precipitation indeterminate"},
+		{"70230", synoptic, types.Remarks{Precip24h: &types.Precip{Inches: 2.3, Hours: 24}},
+			"24-hour precipitation 2.30 in"},
+		{"FZRANO", synoptic, types.Remarks{SensorOutages: []string{"FZRANO"}},
+			"freezing rain information not available"},
+		{"TSNO", synoptic, types.Remarks{SensorOutages: []string{"TSNO"}},
+			"thunderstorm information not available"},
+		{"PNO", synoptic, types.Remarks{SensorOutages: []string{"PNO"}},
+			"precipitation amount not available"},
+		{"$", synoptic, types.Remarks{Maintenance: true}, "station needs maintenance"},
+	}
+	for _, tt := range tests {
+		got, err := DecodeRemarks(strings.Fields(tt.remarks), tt.obs)
+		if err != nil {
+			t.Errorf("%s: %v", tt.remarks, err)
+			continue
+		}
+		want := tt.want
+		want.Raw, want.Readable = []string{}, []string{tt.readable}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s at %s:\n got %+v\nwant %+v", tt.remarks, tt.obs.Format("1504Z"), got, want)
+		}
+	}
+}
+
+func TestDecodeRemarksReport(t *testing.T) {
+	obs := time.Date(2025, 10, 25, 17, 53, 0, 0, time.UTC)
+	got, err := DecodeRemarks(strings.Fields("AO2 SLP132 P0003 60012 T01560083 10178 20111 53012 TSNO PNO $ XYZ"), obs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Station != "AO2" || *got.SeaLevelPressure != 1013.2 || got.PrecipHourly.Inches != 0.03 ||
+		got.Precip3or6h.Hours != 6 || *got.TempExact != 15.6 || *got.Max6h != 17.8 || *got.Min6h != 11.1 ||
+		got.PressureTendency.Change != 1.2 || !got.Maintenance {
+		t.Errorf("decoded %+v", got)
+	}
+	if !slices.Equal(got.SensorOutages, []string{"TSNO", "PNO"}) {
+		t.Errorf("SensorOutages = %v", got.SensorOutages)
+	}
+	if !slices.Equal(got.Raw, []string{"XYZ"}) || len(got.Readable) != 11 {
+		t.Errorf("Raw = %v, Readable = %q", got.Raw, got.Readable)
+	}
+}
@@ -0,0 +1,153 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// BuildInternalTAF normalizes the API's pre-decoded forecast groups
+func BuildInternalTAF(data *types.TAFresponse, output *types.TAF) error {
+	output.ICAO = data.IcaoID
+	output.Raw = data.RawTAF
+	output.Amended = strings.HasPrefix(data.RawTAF, "TAF AMD")
+	output.Corrected = strings.HasPrefix(data.RawTAF, "TAF COR")
+	output.ValidFrom = data.ValidTimeFrom
+	output.ValidTo = data.ValidTimeTo
+	output.Periods = make([]types.TAFPeriod, 0, len(data.Fcsts))
+
+	issued, err := time.Parse(time.RFC3339, data.IssueTime)
+	if err != nil {
+		return fmt.Errorf("BuildInternalTAF issueTime: %w", err)
+	}
+	output.Issued = issued.Unix()
+
+	for i, fcst := range data.Fcsts {
+		period, err := buildTAFPeriod(fcst)
+		if err != nil {
+			return fmt.Errorf("BuildInternalTAF period %d: %w", i, err)
+		}
+		if period.Change == "PROB" {
+			period.Temporary = probTempo(data.RawTAF, period)
+		}
+		output.Periods = append(output.Periods, period)
+	}
+	return nil
+}
+
+func buildTAFPeriod(fcst types.TAFforecast) (types.TAFPeriod, error) {
+	period := types.TAFPeriod{
+		Change: "BASE",
+		From:   fcst.TimeFrom,
+		To:     fcst.TimeTo,
+	}
+	if fcst.FcstChange != nil {
+		period.Change = *fcst.FcstChange
+	}
+	if fcst.Probability != nil {
+		period.Probability = *fcst.Probability
+		if period.Change == "TEMPO" {
+			period.Change, period.Temporary = "PROB", true
+		}
+	}
+	if fcst.TimeBec != nil {
+		period.Becoming = *fcst.TimeBec
+	}
+	if fcst.WxString != nil {
+		period.WxString = *fcst.WxString
+	}
+	if fcst.NotDecoded != nil {
+		period.NotDecoded = *fcst.NotDecoded
+	}
+
+	if fcst.Wspd != nil {
+		wind := windFromAPI(fcst.Wdir, *fcst.Wspd, fcst.Wgst)
+		period.Wind = &wind
+	}
+
+	period.Visibility = fcst.Visib
+
+	if fcst.VertVis != nil {
+		vv := types.Feet(*fcst.VertVis)
+		period.VertVis = &vv
+	}
+
+	if len(fcst.Clouds) > 0 {
+		period.Clouds = make([]types.CloudData, 0, len(fcst.Clouds))
+		for _, layer := range fcst.Clouds {
+			if layer.Base == nil {
+				continue // SKC/NSC carry no base
+			}
+			cloud := types.CloudData{
+				Base:     types.Feet(*layer.Base),
+				Coverage: provideCloudCover(layer.Cover),
+			}
+			if layer.Type != nil {
+				cloud.Type = *layer.Type
+			}
+			period.Clouds = append(period.Clouds, cloud)
+		}
+	}
+
+	if fcst.WshearHgt != nil && fcst.WshearDir != nil && fcst.WshearSpd != nil {
+		period.WindShear = &types.WindShear{
+			Height:    types.Feet(*fcst.WshearHgt),
+			Direction: types.DegTrue(*fcst.WshearDir),
+			Speed:     types.Knots(*fcst.WshearSpd),
+		}
+	}
+
+	for _, it := range fcst.IcgTurb {
+		period.IcingTurb = append(period.IcingTurb, types.IcingTurb{
+			Kind:      it.Var,
+			Intensity: it.Intensity,
+			Base:      types.Feet(it.MinAlt),
+			Top:       types.Feet(it.MaxAlt),
+		})
+	}
+
+	for _, temp := range fcst.Temp {
+		period.Temps = append(period.Temps, types.ForecastTemp{
+			Kind:    strings.ToUpper(temp.MaxOrMin),
+			Celsius: temp.SfcTemp,
+			At:      temp.ValidTime,
+		})
+	}
+
+	return period, nil
+}
+
+// probTempo reports whether the raw TAF gives period as PROB30/40 TEMPO,
+// which the API's fcstChange doesn't tell apart from a plain PROB
+func probTempo(raw string, period types.TAFPeriod) bool {
+	tokens := strings.Fields(raw)
+	want := fmt.Sprintf("PROB%d", period.Probability)
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i] != want || tokens[i+1] != "TEMPO" {
+			continue
+		}
+		from, _, ok, err := parseDayHourPair(tokens[i+2], time.Unix(period.From, 0))
+		if ok && err == nil && from.Unix() == period.From {
+			return true
+		}
+	}
+	return false
+}
+
+// windFromAPI builds wind from the API's direction, speed and gust
+func windFromAPI(wdir *types.WindDir, speed int, gust *int) types.WindData {
+	wind := types.WindData{Speed: types.Knots(speed)}
+	if wdir != nil {
+		wind.Direction = wdir.Degrees
+		wind.Variable = wdir.Variable
+	}
+
+	if gust != nil {
+		gustValue := types.Knots(*gust)
+		wind.Gusts = &gustValue
+	}
+	wind.Calm = wind.Speed == 0 && wind.Direction == 0 && !wind.Variable
+	return wind
+}
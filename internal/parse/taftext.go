@@ -0,0 +1,346 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+var (
+	stationRe   = regexp.MustCompile(`^[A-Z][A-Z0-9]{3}$`)
+	issueTimeRe = regexp.MustCompile(`^(\d{2})(\d{2})(\d{2})Z$`)
+	fromGroupRe = regexp.MustCompile(`^FM(\d{2})(\d{2})(\d{2})$`)
+	probGroupRe = regexp.MustCompile(`^PROB(30|40)$`)
+	tafTempRe   = regexp.MustCompile(`^T([XN])(M?)(\d{2})/(\d{2})(\d{2})Z$`)
+	tafAltimRe  = regexp.MustCompile(`^QNH\d{4}INS$`)
+)
+
+// ParseTAF decodes a raw TAF into the same model BuildInternalTAF builds
+// from the API. ref is any time near issuance and anchors the day-of-month
+// groups to a full date.
+func ParseTAF(raw string, ref time.Time) (types.TAF, error) {
+	raw = strings.TrimSpace(raw)
+	tokens := strings.Fields(strings.TrimSuffix(raw, "="))
+	output := types.TAF{Raw: raw}
+
+	i := 0
+	next := func() string {
+		if i < len(tokens) {
+			return tokens[i]
+		}
+		return ""
+	}
+
+	if next() == "TAF" {
+		i++
+	}
+	for {
+		switch next() {
+		case "AMD":
+			output.Amended = true
+			i++
+			continue
+		case "COR":
+			output.Corrected = true
+			i++
+			continue
+		}
+		break
+	}
+
+	if !stationRe.MatchString(next()) {
+		return types.TAF{}, tokenErr("station", next(), errMalformed)
+	}
+	output.ICAO = next()
+	i++
+
+	if m := issueTimeRe.FindStringSubmatch(next()); m != nil {
+		issued, err := resolveDayTime(ref, atoi(m[1]), atoi(m[2]), atoi(m[3]))
+		if err != nil {
+			return types.TAF{}, tokenErr("issue time", next(), err)
+		}
+		output.Issued = issued.Unix()
+		ref = issued
+		i++
+	}
+
+	if next() == "NIL" {
+		return output, nil
+	}
+
+	validFrom, validTo, ok, err := parseDayHourPair(next(), ref)
+	if err != nil {
+		return types.TAF{}, err
+	}
+	if !ok {
+		return types.TAF{}, tokenErr("valid period", next(), errMalformed)
+	}
+	output.ValidFrom = validFrom.Unix()
+	output.ValidTo = validTo.Unix()
+	i++
+
+	p := &tafBuilder{ref: validFrom, validTo: output.ValidTo}
+	p.open(types.TAFPeriod{Change: "BASE", From: output.ValidFrom})
+
+	for i < len(tokens) {
+		token := tokens[i]
+
+		if token == "RMK" {
+			break
+		}
+
+		if m := fromGroupRe.FindStringSubmatch(token); m != nil {
+			from, err := resolveDayTime(p.ref, atoi(m[1]), atoi(m[2]), atoi(m[3]))
+			if err != nil {
+				return types.TAF{}, tokenErr("FM", token, err)
+			}
+			p.open(types.TAFPeriod{Change: "FM", From: from.Unix()})
+			i++
+			continue
+		}
+
+		change, probability, temporary := "", 0, false
+		if token == "BECMG" || token == "TEMPO" {
+			change = token
+		} else if m := probGroupRe.FindStringSubmatch(token); m != nil {
+			change, probability = "PROB", atoi(m[1])
+			if i+1 < len(tokens) && tokens[i+1] == "TEMPO" {
+				temporary = true
+				i++
+			}
+		}
+		if change != "" {
+			if i+1 >= len(tokens) {
+				return types.TAF{}, tokenErr(change, token, fmt.Errorf("missing period"))
+			}
+			from, to, ok, err := parseDayHourPair(tokens[i+1], p.ref)
+			if err != nil {
+				return types.TAF{}, err
+			}
+			if !ok {
+				return types.TAF{}, tokenErr(change, tokens[i+1], errMalformed)
+			}
+			p.open(types.TAFPeriod{
+				Change:      change,
+				Probability: probability,
+				Temporary:   temporary,
+				From:        from.Unix(),
+				To:          to.Unix(),
+			})
+			i += 2
+			continue
+		}
+
+		used, err := applyTAFElement(p.current(), tokens, i, p.ref)
+		if err != nil {
+			return types.TAF{}, err
+		}
+		i += used
+	}
+
+	output.Periods = p.finish()
+	return output, nil
+}
+
+// tafBuilder tracks which periods are still open-ended: the prevailing
+// BASE/FM group and any BECMG since, all of which run to the next FM
+type tafBuilder struct {
+	ref     time.Time
+	validTo int64
+	periods []types.TAFPeriod
+	running []int
+}
+
+func (b *tafBuilder) open(period types.TAFPeriod) {
+	switch period.Change {
+	case "BASE", "FM":
+		b.closeRunning(period.From)
+		b.running = append(b.running, len(b.periods))
+	case "BECMG":
+		// BECMG's own period is the transition; the result persists
+		period.Becoming = period.To
+		period.To = 0
+		b.running = append(b.running, len(b.periods))
+	}
+	b.periods = append(b.periods, period)
+	b.ref = time.Unix(period.From, 0)
+}
+
+func (b *tafBuilder) closeRunning(at int64) {
+	for _, idx := range b.running {
+		b.periods[idx].To = at
+	}
+	b.running = b.running[:0]
+}
+
+func (b *tafBuilder) current() *types.TAFPeriod {
+	return &b.periods[len(b.periods)-1]
+}
+
+func (b *tafBuilder) finish() []types.TAFPeriod {
+	b.closeRunning(b.validTo)
+	for i := range b.periods {
+		b.periods[i].NotDecoded = strings.TrimSpace(b.periods[i].NotDecoded)
+	}
+	return b.periods
+}
+
+// applyTAFElement decodes one forecast element into period, returning the
+// number of tokens consumed. Unrecognized tokens land in NotDecoded.
+func applyTAFElement(period *types.TAFPeriod, tokens []string, i int, ref time.Time) (int, error) {
+	token := tokens[i]
+
+	if wind, ok, err := parseWindGroup(token); ok {
+		if err != nil {
+			return 0, err
+		}
+		period.Wind = &wind
+		return 1, nil
+	}
+
+	vis, used, err := parseVisibilityGroup(tokens, i)
+	if err != nil {
+		return 0, err
+	}
+	if used > 0 {
+		period.Visibility = &vis
+		if token == "CAVOK" {
+			period.Clouds = []types.CloudData{}
+			if period.Change != "BASE" && period.Change != "FM" {
+				period.WxString = "NSW"
+			}
+		}
+		return used, nil
+	}
+
+	if sky, ok, err := parseSkyGroup(token); ok {
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case sky.clear:
+			period.Clouds = []types.CloudData{}
+		case sky.vertVis != nil:
+			period.VertVis = sky.vertVis
+		default:
+			period.Clouds = append(period.Clouds, *sky.layer)
+		}
+		return 1, nil
+	}
+
+	if shear, ok, err := parseWindShearGroup(token); ok {
+		if err != nil {
+			return 0, err
+		}
+		period.WindShear = shear
+		return 1, nil
+	}
+
+	if m := tafTempRe.FindStringSubmatch(token); m != nil {
+		at, err := resolveDayTime(ref, atoi(m[4]), atoi(m[5]), 0)
+		if err != nil {
+			return 0, tokenErr("temperature", token, err)
+		}
+		temp := types.ForecastTemp{Kind: "MAX", Celsius: float64(atoi(m[3])), At: at.Unix()}
+		if m[1] == "N" {
+			temp.Kind = "MIN"
+		}
+		if m[2] == "M" {
+			temp.Celsius = -temp.Celsius
+		}
+		period.Temps = append(period.Temps, temp)
+		return 1, nil
+	}
+
+	switch {
+	case token == "NSW":
+		period.WxString = "NSW"
+	case isWeatherToken(token):
+		period.WxString = strings.TrimSpace(period.WxString + " " + token)
+	case tafAltimRe.MatchString(token):
+		// military QNH isn't modelled
+	default:
+		period.NotDecoded += " " + token
+	}
+	return 1, nil
+}
+
+func atoi(s string) int {
+	v, _ := strconv.Atoi(s)
+	return v
+}
+
+// DiffTAF lists where two decodes of the same TAF disagree, e.g. the API's
+// fcsts against ParseTAF of its rawTAF
+func DiffTAF(a, b types.TAF) []string {
+	var diffs []string
+	if len(a.Periods) != len(b.Periods) {
+		return append(diffs, fmt.Sprintf("period count %d vs %d", len(a.Periods), len(b.Periods)))
+	}
+	if a.ValidFrom != b.ValidFrom || a.ValidTo != b.ValidTo {
+		diffs = append(diffs, "valid period")
+	}
+
+	for i := range a.Periods {
+		pa, pb := a.Periods[i], b.Periods[i]
+		label := fmt.Sprintf("period %d (%s)", i, pa.Change)
+		if pa.Change != pb.Change || pa.Probability != pb.Probability || pa.Temporary != pb.Temporary {
+			diffs = append(diffs, label+": change type")
+		}
+		if pa.From != pb.From || pa.To != pb.To {
+			diffs = append(diffs, label+": times")
+		}
+		if !sameWind(pa.Wind, pb.Wind) {
+			diffs = append(diffs, label+": wind")
+		}
+		if !samePtr(pa.Visibility, pb.Visibility) {
+			diffs = append(diffs, label+": visibility")
+		}
+		if pa.WxString != pb.WxString {
+			diffs = append(diffs, label+": weather")
+		}
+		if !sameClouds(pa.Clouds, pb.Clouds) || !samePtr(pa.VertVis, pb.VertVis) {
+			diffs = append(diffs, label+": sky")
+		}
+		if (pa.WindShear == nil) != (pb.WindShear == nil) ||
+			(pa.WindShear != nil && *pa.WindShear != *pb.WindShear) {
+			diffs = append(diffs, label+": wind shear")
+		}
+		if !slices.Equal(pa.Temps, pb.Temps) {
+			diffs = append(diffs, label+": temperatures")
+		}
+	}
+	return diffs
+}
+
+func sameWind(a, b *types.WindData) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Direction == b.Direction && a.Speed == b.Speed &&
+		a.Variable == b.Variable && samePtr(a.Gusts, b.Gusts)
+}
+
+func samePtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameClouds(a, b []types.CloudData) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Base != b[i].Base || a[i].Coverage != b[i].Coverage {
+			return false
+		}
+	}
+	return true
+}
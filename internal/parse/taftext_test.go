@@ -0,0 +1,135 @@
+package parse
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// testdataTAFs decodes every testdata/taf*.json record
+func testdataTAFs(t *testing.T) []types.TAFresponse {
+	t.Helper()
+	paths, err := filepath.Glob("../../testdata/taf*.json")
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no TAF testdata: %v", err)
+	}
+	var all []types.TAFresponse
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []types.TAFresponse
+		if err := json.Unmarshal(raw, &records); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		all = append(all, records...)
+	}
+	return all
+}
+
+func TestParseTAFMatchesAPI(t *testing.T) {
+	for _, record := range testdataTAFs(t) {
+		t.Run(record.IcaoID+" "+record.IssueTime, func(t *testing.T) {
+			var api types.TAF
+			if err := BuildInternalTAF(&record, &api); err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := ParseTAF(record.RawTAF, time.Unix(api.Issued, 0))
+			if err != nil {
+				t.Fatalf("ParseTAF: %v", err)
+			}
+			if diffs := DiffTAF(api, parsed); len(diffs) > 0 {
+				t.Errorf("%s\ndiffs: %v", record.RawTAF, diffs)
+			}
+			if parsed.Issued != api.Issued {
+				t.Errorf("issued %d, API %d", parsed.Issued, api.Issued)
+			}
+		})
+	}
+}
+
+func TestParseTAFChangeGroups(t *testing.T) {
+	ref := time.Date(2025, 10, 25, 17, 0, 0, 0, time.UTC)
+	tests := []struct {
+		raw         string
+		change      string
+		probability int
+		temporary   bool
+	}{
+		{"TAF KXYZ 251720Z 2518/2618 08009KT P6SM BKN110 PROB30 2611/2615 5SM -SHRA", "PROB", 30, false},
+		{"TAF KXYZ 251720Z 2518/2618 08009KT P6SM BKN110 PROB40 TEMPO 2611/2615 2SM TSRA", "PROB", 40, true},
+		{"TAF KXYZ 251720Z 2518/2618 08009KT P6SM BKN110 TEMPO 2611/2615 5SM -SHRA", "TEMPO", 0, false},
+		{"TAF KXYZ 251720Z 2518/2618 08009KT P6SM BKN110 BECMG 2611/2613 OVC030", "BECMG", 0, false},
+	}
+	for _, tt := range tests {
+		taf, err := ParseTAF(tt.raw, ref)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.raw, err)
+		}
+		if len(taf.Periods) != 2 {
+			t.Fatalf("%s: %d periods", tt.raw, len(taf.Periods))
+		}
+		p := taf.Periods[1]
+		if p.Change != tt.change || p.Probability != tt.probability || p.Temporary != tt.temporary {
+			t.Errorf("%s: got %s %d temporary=%v", tt.raw, p.Change, p.Probability, p.Temporary)
+		}
+		if p.Wind != nil || p.Visibility == nil && p.Clouds == nil {
+			t.Errorf("%s: group elements not decoded: %+v", tt.raw, p)
+		}
+	}
+}
+
+func TestProbTempoFromAPI(t *testing.T) {
+	at := func(day, hour int) int64 { return time.Date(2025, 10, day, hour, 0, 0, 0, time.UTC).Unix() }
+	prob, probability := "PROB", 40
+	record := types.TAFresponse{
+		IcaoID:        "KXYZ",
+		IssueTime:     "2025-10-25T17:20:00Z",
+		ValidTimeFrom: at(25, 18),
+		ValidTimeTo:   at(26, 18),
+		RawTAF:        "TAF KXYZ 251720Z 2518/2618 08009KT P6SM BKN110 PROB40 TEMPO 2611/2615 2SM TSRA",
+		Fcsts: []types.TAFforecast{
+			{TimeFrom: at(25, 18), TimeTo: at(26, 18)},
+			{TimeFrom: at(26, 11), TimeTo: at(26, 15), FcstChange: &prob, Probability: &probability},
+		},
+	}
+	var api types.TAF
+	if err := BuildInternalTAF(&record, &api); err != nil {
+		t.Fatal(err)
+	}
+	if p := api.Periods[1]; p.Change != "PROB" || !p.Temporary {
+		t.Errorf("API period = %s temporary=%v", p.Change, p.Temporary)
+	}
+
+	plain := api
+	plain.Periods = slices.Clone(api.Periods)
+	plain.Periods[1].Temporary = false
+	if diffs := DiffTAF(api, plain); len(diffs) != 1 || diffs[0] != "period 1 (PROB): change type" {
+		t.Errorf("DiffTAF ignored a lost TEMPO: %v", diffs)
+	}
+}
+
+func TestForecastTemps(t *testing.T) {
+	ref := time.Date(2025, 10, 25, 17, 0, 0, 0, time.UTC)
+	taf, err := ParseTAF("TAF KXYZ 251720Z 2518/2624 20010KT P6SM SKC TX24/2521Z TNM02/2611Z", ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.ForecastTemp{
+		{Kind: "MAX", Celsius: 24, At: time.Date(2025, 10, 25, 21, 0, 0, 0, time.UTC).Unix()},
+		{Kind: "MIN", Celsius: -2, At: time.Date(2025, 10, 26, 11, 0, 0, 0, time.UTC).Unix()},
+	}
+	got := taf.Periods[0].Temps
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Temps = %+v, want %+v", got, want)
+	}
+	if taf.Periods[0].NotDecoded != "" {
+		t.Errorf("NotDecoded = %q", taf.Periods[0].NotDecoded)
+	}
+}
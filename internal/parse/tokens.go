@@ -0,0 +1,304 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// TokenError names the token that failed to decode and the group it was
+// expected to be
+type TokenError struct {
+	Group string
+	Token string
+	Err   error
+}
+
+func (e *TokenError) Error() string {
+	return fmt.Sprintf("%s group %q: %v", e.Group, e.Token, e.Err)
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errMalformed = errors.New("malformed")
+	errRange     = errors.New("out of range")
+)
+
+func tokenErr(group, token string, err error) *TokenError {
+	return &TokenError{Group: group, Token: token, Err: err}
+}
+
+const (
+	ktPerMPS = 1.94384
+	ktPerKMH = 0.539957
+)
+
+var (
+	windRe        = regexp.MustCompile(`^(\d{3}|VRB)(\d{2,3})(?:G(\d{2,3}))?(KT|MPS|KMH)$`)
+	windVarRe     = regexp.MustCompile(`^(\d{3})V(\d{3})$`)
+	visSMRe       = regexp.MustCompile(`^([PM])?(\d+)SM$`)
+	visFracRe     = regexp.MustCompile(`^([PM])?(\d+)/(\d+)SM$`)
+	visWholeRe    = regexp.MustCompile(`^\d$`)
+	visMetricRe   = regexp.MustCompile(`^(\d{4})(NDV)?$`)
+	visDirRe      = regexp.MustCompile(`^(\d{4})(N|NE|E|SE|S|SW|W|NW)$`)
+	skyLayerRe    = regexp.MustCompile(`^(FEW|SCT|BKN|OVC)(\d{3}|///)(CB|TCU|///)?$`)
+	skyVVRe       = regexp.MustCompile(`^VV(\d{3}|///)$`)
+	windShearRe   = regexp.MustCompile(`^WS(\d{3})/(\d{3})(\d{2,3})KT$`)
+	weatherRe     = regexp.MustCompile(`^(\+|-|VC)?(MI|PR|BC|DR|BL|SH|TS|FZ)?((?:DZ|RA|SN|SG|IC|PL|GR|GS|UP|BR|FG|FU|VA|DU|SA|HZ|PY|PO|SQ|FC|SS|DS)*)$`)
+	dayHourPairRe = regexp.MustCompile(`^(\d{2})(\d{2})/(\d{2})(\d{2})$`)
+)
+
+// parseWindGroup reads dddff[Ggg]KT, also in MPS/KMH (converted to knots)
+func parseWindGroup(token string) (types.WindData, bool, error) {
+	m := windRe.FindStringSubmatch(token)
+	if m == nil {
+		return types.WindData{}, false, nil
+	}
+
+	factor := 1.0
+	switch m[4] {
+	case "MPS":
+		factor = ktPerMPS
+	case "KMH":
+		factor = ktPerKMH
+	}
+	convert := func(s string) types.Knots {
+		v, _ := strconv.Atoi(s)
+		return types.Knots(float64(v)*factor + 0.5)
+	}
+
+	var wind types.WindData
+	if m[1] == "VRB" {
+		wind.Variable = true
+	} else {
+		dir, _ := strconv.Atoi(m[1])
+		if dir > 360 {
+			return types.WindData{}, true, tokenErr("wind", token, errRange)
+		}
+		wind.Direction = types.DegTrue(dir)
+	}
+	wind.Speed = convert(m[2])
+	if m[3] != "" {
+		gusts := convert(m[3])
+		wind.Gusts = &gusts
+	}
+	wind.Calm = wind.Speed == 0 && wind.Direction == 0 && !wind.Variable
+	return wind, true, nil
+}
+
+// parseWindVariation reads the dddVddd sector that may follow the wind
+func parseWindVariation(token string) (from, to types.DegTrue, ok bool) {
+	m := windVarRe.FindStringSubmatch(token)
+	if m == nil {
+		return 0, 0, false
+	}
+	f, _ := strconv.Atoi(m[1])
+	t, _ := strconv.Atoi(m[2])
+	if f > 360 || t > 360 {
+		return 0, 0, false
+	}
+	return types.DegTrue(f), types.DegTrue(t), true
+}
+
+// parseVisibilityGroup reads visibility starting at tokens[i], returning
+// how many tokens it used (two for "1 1/2SM")
+func parseVisibilityGroup(tokens []string, i int) (types.Visibility, int, error) {
+	token := tokens[i]
+
+	if token == "CAVOK" {
+		return types.VisMeters(9999), 1, nil
+	}
+
+	if visWholeRe.MatchString(token) && i+1 < len(tokens) {
+		if m := visFracRe.FindStringSubmatch(tokens[i+1]); m != nil && m[1] == "" {
+			frac, err := fraction(m[2], m[3])
+			if err != nil {
+				return types.Visibility{}, 0, tokenErr("visibility", token+" "+tokens[i+1], err)
+			}
+			whole, _ := strconv.Atoi(token)
+			return types.VisMiles(float64(whole) + frac), 2, nil
+		}
+	}
+
+	if m := visSMRe.FindStringSubmatch(token); m != nil {
+		miles, _ := strconv.Atoi(m[2])
+		vis := types.VisMiles(float64(miles))
+		vis.Plus, vis.Minus = m[1] == "P", m[1] == "M"
+		return vis, 1, nil
+	}
+
+	if m := visFracRe.FindStringSubmatch(token); m != nil {
+		frac, err := fraction(m[2], m[3])
+		if err != nil {
+			return types.Visibility{}, 0, tokenErr("visibility", token, err)
+		}
+		vis := types.VisMiles(frac)
+		vis.Plus, vis.Minus = m[1] == "P", m[1] == "M"
+		return vis, 1, nil
+	}
+
+	if m := visMetricRe.FindStringSubmatch(token); m != nil {
+		meters, _ := strconv.Atoi(m[1])
+		vis := types.VisMeters(meters)
+		if i+1 < len(tokens) {
+			if d := visDirRe.FindStringSubmatch(tokens[i+1]); d != nil {
+				vis.Directional = &types.DirectionalVis{Meters: atoi(d[1]), Direction: d[2]}
+				return vis, 2, nil
+			}
+		}
+		return vis, 1, nil
+	}
+
+	return types.Visibility{}, 0, nil
+}
+
+func fraction(num, den string) (float64, error) {
+	n, _ := strconv.Atoi(num)
+	d, _ := strconv.Atoi(den)
+	if d == 0 || n > d {
+		return 0, errMalformed
+	}
+	return float64(n) / float64(d), nil
+}
+
+type sky struct {
+	layer   *types.CloudData
+	vertVis *types.Feet
+	clear   bool // SKC, CLR, NSC, NCD
+}
+
+// parseSkyGroup reads one cloud layer, vertical visibility, or clear token
+func parseSkyGroup(token string) (sky, bool, error) {
+	switch token {
+	case "SKC", "CLR", "NSC", "NCD":
+		return sky{clear: true}, true, nil
+	}
+
+	if m := skyVVRe.FindStringSubmatch(token); m != nil {
+		height, err := hundredsOfFeet(m[1])
+		if err != nil {
+			return sky{}, true, tokenErr("vertical visibility", token, err)
+		}
+		return sky{vertVis: &height}, true, nil
+	}
+
+	m := skyLayerRe.FindStringSubmatch(token)
+	if m == nil {
+		return sky{}, false, nil
+	}
+	base, err := hundredsOfFeet(m[2])
+	if err != nil {
+		return sky{}, true, tokenErr("sky", token, err)
+	}
+	cloudType := m[3]
+	if cloudType == "///" {
+		cloudType = ""
+	}
+	return sky{layer: &types.CloudData{
+		Base:     base,
+		Coverage: provideCloudCover(m[1]),
+		Type:     cloudType,
+	}}, true, nil
+}
+
+func hundredsOfFeet(s string) (types.Feet, error) {
+	if s == "///" {
+		return 0, errors.New("height not reported")
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errMalformed
+	}
+	return types.Feet(v * 100), nil
+}
+
+// parseWindShearGroup reads the TAF WShhh/dddffKT group
+func parseWindShearGroup(token string) (*types.WindShear, bool, error) {
+	m := windShearRe.FindStringSubmatch(token)
+	if m == nil {
+		if strings.HasPrefix(token, "WS") && strings.Contains(token, "/") {
+			return nil, true, tokenErr("wind shear", token, errMalformed)
+		}
+		return nil, false, nil
+	}
+	height, _ := hundredsOfFeet(m[1])
+	dir, _ := strconv.Atoi(m[2])
+	speed, _ := strconv.Atoi(m[3])
+	return &types.WindShear{
+		Height:    height,
+		Direction: types.DegTrue(dir),
+		Speed:     types.Knots(speed),
+	}, true, nil
+}
+
+// isWeatherToken matches a present-weather group like -SHRA, VCTS or BR
+func isWeatherToken(token string) bool {
+	m := weatherRe.FindStringSubmatch(token)
+	if m == nil {
+		return false
+	}
+	// an intensity or proximity prefix alone is not weather
+	return m[2] != "" || m[3] != ""
+}
+
+// resolveDayTime places a day-of-month/hour/minute from a report on the
+// calendar month nearest ref. Hour 24 is the end of that day.
+func resolveDayTime(ref time.Time, day, hour, minute int) (time.Time, error) {
+	if day < 1 || day > 31 || hour > 24 || minute > 59 {
+		return time.Time{}, errRange
+	}
+
+	ref = ref.UTC()
+	var best time.Time
+	for offset := -1; offset <= 1; offset++ {
+		first := time.Date(ref.Year(), ref.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		candidate := first.AddDate(0, 0, day-1)
+		if candidate.Month() != first.Month() {
+			continue // e.g. the 31st of a 30-day month
+		}
+		candidate = candidate.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		if best.IsZero() || absDuration(candidate.Sub(ref)) < absDuration(best.Sub(ref)) {
+			best = candidate
+		}
+	}
+	if best.IsZero() {
+		return time.Time{}, errRange
+	}
+	return best, nil
+}
+
+// parseDayHourPair reads a DDHH/DDHH validity or change period
+func parseDayHourPair(token string, ref time.Time) (from, to time.Time, ok bool, err error) {
+	m := dayHourPairRe.FindStringSubmatch(token)
+	if m == nil {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	n := make([]int, 4)
+	for i := range n {
+		n[i], _ = strconv.Atoi(m[i+1])
+	}
+	from, err = resolveDayTime(ref, n[0], n[1], 0)
+	if err != nil {
+		return time.Time{}, time.Time{}, true, tokenErr("period", token, err)
+	}
+	to, err = resolveDayTime(from, n[2], n[3], 0)
+	if err != nil || !to.After(from) {
+		return time.Time{}, time.Time{}, true, tokenErr("period", token, errRange)
+	}
+	return from, to, true, nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
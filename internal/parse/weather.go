@@ -0,0 +1,159 @@
+package parse
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+var descriptorNames = map[string]string{
+	"MI": "shallow",
+	"PR": "partial",
+	"BC": "patches of",
+	"DR": "low drifting",
+	"BL": "blowing",
+	"SH": "showers",
+	"TS": "thunderstorm",
+	"FZ": "freezing",
+}
+
+type phenomenon struct {
+	name string
+	kind int
+}
+
+const (
+	precipitation = iota
+	obscuration
+	other
+)
+
+var phenomena = map[string]phenomenon{
+	"DZ": {"drizzle", precipitation},
+	"RA": {"rain", precipitation},
+	"SN": {"snow", precipitation},
+	"SG": {"snow grains", precipitation},
+	"IC": {"ice crystals", precipitation},
+	"PL": {"ice pellets", precipitation},
+	"GR": {"hail", precipitation},
+	"GS": {"small hail", precipitation},
+	"UP": {"unknown precipitation", precipitation},
+	"BR": {"mist", obscuration},
+	"FG": {"fog", obscuration},
+	"FU": {"smoke", obscuration},
+	"VA": {"volcanic ash", obscuration},
+	"DU": {"dust", obscuration},
+	"SA": {"sand", obscuration},
+	"HZ": {"haze", obscuration},
+	"PY": {"spray", obscuration},
+	"PO": {"dust whirls", other},
+	"SQ": {"squalls", other},
+	"FC": {"funnel cloud", other},
+	"SS": {"sandstorm", other},
+	"DS": {"duststorm", other},
+}
+
+var severePhenomena = []string{"GR", "SQ", "FC", "SS", "DS", "VA"}
+
+// DecodeWeather decodes a space-separated present-weather string such as
+// "-SN BR". Groups that don't decode are skipped and reported in err.
+func DecodeWeather(wxString string) ([]types.Weather, error) {
+	weather := []types.Weather{}
+	var errs []error
+	for token := range strings.FieldsSeq(wxString) {
+		wx, err := decodeWeatherGroup(token)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		weather = append(weather, wx)
+	}
+	return weather, errors.Join(errs...)
+}
+
+func decodeWeatherGroup(token string) (types.Weather, error) {
+	m := weatherRe.FindStringSubmatch(token)
+	if m == nil || (m[2] == "" && m[3] == "") {
+		return types.Weather{}, tokenErr("weather", token, errMalformed)
+	}
+
+	wx := types.Weather{Raw: token, Descriptor: m[2], Intensity: "moderate"}
+	switch m[1] {
+	case "-":
+		wx.Intensity = "light"
+	case "+":
+		wx.Intensity = "heavy"
+	case "VC":
+		wx.Vicinity = true
+	}
+
+	var names []string
+	for code := range weatherCodes(m[3]) {
+		p := phenomena[code]
+		wx.Phenomena = append(wx.Phenomena, code)
+		names = append(names, p.name)
+		switch p.kind {
+		case precipitation:
+			wx.Precipitation = true
+		case obscuration:
+			wx.Obscuration = true
+		}
+		if slices.Contains(severePhenomena, code) {
+			wx.Severe = true
+		}
+	}
+	wx.Thunderstorm = wx.Descriptor == "TS"
+	wx.FreezingPrecip = wx.Descriptor == "FZ" && wx.Precipitation
+	if !wx.Precipitation && m[1] != "+" {
+		// intensity is only reported for precipitation (and +FC, +SS, +DS)
+		wx.Intensity = ""
+	}
+
+	wx.Text = weatherText(wx, names)
+	return wx, nil
+}
+
+// weatherCodes yields the two-letter codes of a compound group like "RASN"
+func weatherCodes(codes string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for i := 0; i+2 <= len(codes); i += 2 {
+			if !yield(codes[i : i+2]) {
+				return
+			}
+		}
+	}
+}
+
+func weatherText(wx types.Weather, names []string) string {
+	what := strings.Join(names, " and ")
+	intensity := ""
+	if wx.Intensity == "light" || wx.Intensity == "heavy" {
+		intensity = wx.Intensity + " "
+	}
+
+	var text string
+	switch {
+	case len(wx.Phenomena) == 1 && wx.Phenomena[0] == "FC" && wx.Intensity == "heavy":
+		text = "tornado or waterspout"
+	case wx.Descriptor == "TS" && what == "":
+		text = "thunderstorm"
+	case wx.Descriptor == "TS":
+		text = "thunderstorm with " + intensity + what
+	case wx.Descriptor == "SH" && what == "":
+		text = "showers"
+	case wx.Descriptor == "SH":
+		text = intensity + what + " showers"
+	case wx.Descriptor != "":
+		text = intensity + descriptorNames[wx.Descriptor] + " " + what
+	default:
+		text = intensity + what
+	}
+
+	if wx.Vicinity {
+		text += " in the vicinity"
+	}
+	return strings.TrimSpace(text)
+}
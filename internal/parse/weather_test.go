@@ -0,0 +1,82 @@
+package parse
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDecodeWeather(t *testing.T) {
+	tests := []struct {
+		token          string
+		intensity      string
+		vicinity       bool
+		descriptor     string
+		phenomena      []string
+		text           string
+		precip, freeze bool
+		thunder        bool
+		severe         bool
+	}{
+		{"RA", "moderate", false, "", []string{"RA"}, "rain", true, false, false, false},
+		{"-SN", "light", false, "", []string{"SN"}, "light snow", true, false, false, false},
+		{"+RA", "heavy", false, "", []string{"RA"}, "heavy rain", true, false, false, false},
+		{"BR", "", false, "", []string{"BR"}, "mist", false, false, false, false},
+		{"-FZRA", "light", false, "FZ", []string{"RA"}, "light freezing rain", true, true, false, false},
+		{"FZDZ", "moderate", false, "FZ", []string{"DZ"}, "freezing drizzle", true, true, false, false},
+		{"FZFG", "", false, "FZ", []string{"FG"}, "freezing fog", false, false, false, false},
+		{"-SHRASN", "light", false, "SH", []string{"RA", "SN"}, "light rain and snow showers", true, false, false, false},
+		{"+TSRA", "heavy", false, "TS", []string{"RA"}, "thunderstorm with heavy rain", true, false, true, false},
+		{"TSGR", "moderate", false, "TS", []string{"GR"}, "thunderstorm with hail", true, false, true, true},
+		{"TS", "", false, "TS", nil, "thunderstorm", false, false, true, false},
+		{"VCTS", "", true, "TS", nil, "thunderstorm in the vicinity", false, false, true, false},
+		{"VCSH", "", true, "SH", nil, "showers in the vicinity", false, false, false, false},
+		{"VCFG", "", true, "", []string{"FG"}, "fog in the vicinity", false, false, false, false},
+		{"BLSN", "moderate", false, "BL", []string{"SN"}, "blowing snow", true, false, false, false},
+		{"+FC", "heavy", false, "", []string{"FC"}, "tornado or waterspout", false, false, false, true},
+		{"+SS", "heavy", false, "", []string{"SS"}, "heavy sandstorm", false, false, false, true},
+	}
+	for _, tt := range tests {
+		weather, err := DecodeWeather(tt.token)
+		if err != nil || len(weather) != 1 {
+			t.Errorf("%s: %+v, %v", tt.token, weather, err)
+			continue
+		}
+		wx := weather[0]
+		if wx.Raw != tt.token || wx.Intensity != tt.intensity || wx.Vicinity != tt.vicinity ||
+			wx.Descriptor != tt.descriptor || !slices.Equal(wx.Phenomena, tt.phenomena) || wx.Text != tt.text {
+			t.Errorf("%s: %+v", tt.token, wx)
+		}
+		if wx.Precipitation != tt.precip || wx.FreezingPrecip != tt.freeze || wx.Thunderstorm != tt.thunder || wx.Severe != tt.severe {
+			t.Errorf("%s: precip=%v freezing=%v thunderstorm=%v severe=%v", tt.token,
+				wx.Precipitation, wx.FreezingPrecip, wx.Thunderstorm, wx.Severe)
+		}
+	}
+}
+
+func TestDecodeWeatherString(t *testing.T) {
+	weather, err := DecodeWeather("-SN  BR")
+	if err != nil || len(weather) != 2 || weather[0].Text != "light snow" || weather[1].Text != "mist" {
+		t.Errorf("DecodeWeather = %+v, %v", weather, err)
+	}
+
+	if weather, err := DecodeWeather(""); err != nil || len(weather) != 0 || weather == nil {
+		t.Errorf("empty string = %#v, %v", weather, err)
+	}
+
+	// unknown groups are skipped and reported; the rest still decode
+	weather, err = DecodeWeather("-RA XX VC RAXX + BR")
+	if len(weather) != 2 || weather[0].Raw != "-RA" || weather[1].Raw != "BR" {
+		t.Errorf("decoded %+v", weather)
+	}
+	var tokens []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var tokenErr *TokenError
+		if errors.As(e, &tokenErr) {
+			tokens = append(tokens, tokenErr.Token)
+		}
+	}
+	if !slices.Equal(tokens, []string{"XX", "VC", "RAXX", "+"}) {
+		t.Errorf("reported %v (err %v)", tokens, err)
+	}
+}
@@ -0,0 +1,152 @@
+// 'runways' looks up an airport's runways in the embedded OurAirports
+// extract and resolves the wind onto each runway end.
+package runways
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/house-holder/pilot-bar/internal/geo"
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+// runways.csv is regenerated from the OurAirports runways.csv, which
+// go generate downloads. The checked-in file holds only the fixture
+// airports and leaves the true headings blank, so their ends fall back to
+// the ident (magnetic) until it is regenerated.
+//
+//go:generate go run ../../cmd/genrunways --url https://davidmegginson.github.io/ourairports-data/runways.csv --out runways.csv --prefix K,P
+//go:embed runways.csv
+var data []byte
+
+// End is one direction of a runway. Where the source has no true heading
+// it is taken from the ident, which makes it magnetic.
+type End struct {
+	Ident    string
+	Heading  float64
+	Magnetic bool
+}
+
+type Runway struct {
+	Length  types.Feet
+	Width   types.Feet
+	Surface string
+	Ends    [2]End
+}
+
+var (
+	loadOnce  sync.Once
+	byAirport map[string][]Runway
+	loadErr   error
+)
+
+// For returns the airport's open runways, none if it isn't in the data
+func For(icao string) ([]Runway, error) {
+	loadOnce.Do(func() {
+		byAirport, loadErr = parse(data)
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return byAirport[strings.ToUpper(icao)], nil
+}
+
+func parse(data []byte) (map[string][]Runway, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("runways.csv: %w", err)
+	}
+	out := map[string][]Runway{}
+	for i, record := range records {
+		if i == 0 {
+			continue // header
+		}
+		if len(record) != 8 {
+			return nil, fmt.Errorf("runways.csv line %d: %d fields", i+1, len(record))
+		}
+		length, _ := strconv.Atoi(record[1])
+		width, _ := strconv.Atoi(record[2])
+		out[record[0]] = append(out[record[0]], Runway{
+			Length:  types.Feet(length),
+			Width:   types.Feet(width),
+			Surface: record[3],
+			Ends: [2]End{
+				end(record[4], record[5]),
+				end(record[6], record[7]),
+			},
+		})
+	}
+	return out, nil
+}
+
+func end(ident, headingTrue string) End {
+	if h, err := strconv.ParseFloat(headingTrue, 64); err == nil {
+		return End{Ident: ident, Heading: h}
+	}
+	number, _ := strconv.Atoi(strings.TrimRight(ident, "LCR"))
+	return End{Ident: ident, Heading: float64(number * 10), Magnetic: true}
+}
+
+// Components resolves the (true) wind onto every runway end, most headwind
+// first and then least crosswind. declination, east positive, relates the
+// true and magnetic headings. A variable wind has no direction to resolve.
+func Components(runways []Runway, wind types.WindData, declination float64) []types.RunwayWind {
+	if wind.Variable || len(runways) == 0 {
+		return nil
+	}
+
+	var out []types.RunwayWind
+	for _, runway := range runways {
+		for _, end := range runway.Ends {
+			out = append(out, component(end, runway.Length, wind, declination))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b types.RunwayWind) int {
+		switch {
+		case a.Headwind != b.Headwind:
+			return int(b.Headwind - a.Headwind)
+		case a.Crosswind != b.Crosswind:
+			return int(a.Crosswind - b.Crosswind)
+		default:
+			return int(b.Length - a.Length)
+		}
+	})
+	return out
+}
+
+func component(end End, length types.Feet, wind types.WindData, declination float64) types.RunwayWind {
+	rw := types.RunwayWind{Runway: end.Ident, Length: length}
+	if end.Magnetic {
+		rw.Heading = geo.MagneticToTrue(end.Heading, declination)
+		rw.HeadingMag = end.Heading
+	} else {
+		rw.Heading = end.Heading
+		rw.HeadingMag = geo.TrueToMagnetic(end.Heading, declination)
+	}
+	if wind.Calm {
+		return rw
+	}
+
+	angle := (float64(wind.Direction) - rw.Heading) * math.Pi / 180
+	head, cross := math.Cos(angle), math.Sin(angle)
+	rw.Headwind = types.Knots(math.Round(float64(wind.Speed) * head))
+	rw.Crosswind = types.Knots(math.Round(math.Abs(float64(wind.Speed) * cross)))
+	if rw.Crosswind > 0 {
+		rw.CrossFrom = "R"
+		if cross < 0 {
+			rw.CrossFrom = "L"
+		}
+	}
+	if wind.Gusts != nil {
+		gust := types.Knots(math.Round(math.Abs(float64(*wind.Gusts) * cross)))
+		rw.GustCrosswind = &gust
+	}
+	return rw
+}
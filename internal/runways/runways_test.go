@@ -0,0 +1,116 @@
+package runways
+
+import (
+	"testing"
+
+	"github.com/house-holder/pilot-bar/pkg/types"
+)
+
+func TestFor(t *testing.T) {
+	list, err := For("ksgf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("KSGF has %d runways, want 2", len(list))
+	}
+	rw := list[0]
+	if rw.Length != 8000 || rw.Surface != "CON" || rw.Ends[0].Ident != "14" || rw.Ends[1].Ident != "32" {
+		t.Errorf("KSGF first runway = %+v", rw)
+	}
+
+	none, err := For("KXYZ")
+	if err != nil || len(none) != 0 {
+		t.Errorf("For(KXYZ) = %v, %v; want nothing", none, err)
+	}
+}
+
+func TestEnd(t *testing.T) {
+	tests := []struct {
+		ident, heading string
+		want           End
+	}{
+		{"14R", "155.3", End{Ident: "14R", Heading: 155.3}},
+		{"32L", "", End{Ident: "32L", Heading: 320, Magnetic: true}},
+		{"02", "", End{Ident: "02", Heading: 20, Magnetic: true}},
+		{"15C", "", End{Ident: "15C", Heading: 150, Magnetic: true}},
+	}
+	for _, tt := range tests {
+		if got := end(tt.ident, tt.heading); got != tt.want {
+			t.Errorf("end(%q, %q) = %+v, want %+v", tt.ident, tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestComponents(t *testing.T) {
+	// KSGF: 14/32 and 02/20, ident headings
+	ksgf, err := For("KSGF")
+	if err != nil {
+		t.Fatal(err)
+	}
+	knots := func(k types.Knots) *types.Knots { return &k }
+
+	type want struct {
+		runway          string
+		head, cross     types.Knots
+		from            string
+		gust            *types.Knots
+		heading, magHdg float64
+	}
+	tests := []struct {
+		name        string
+		wind        types.WindData
+		declination float64
+		want        []want
+	}{
+		{"headwind first, tailwinds last", types.WindData{Direction: 140, Speed: 10}, 0, []want{
+			{"14", 10, 0, "", nil, 140, 140},
+			{"20", 5, 9, "L", nil, 200, 200},
+			{"02", -5, 9, "R", nil, 20, 20},
+			{"32", -10, 0, "", nil, 320, 320},
+		}},
+		{"gust crosswind", types.WindData{Direction: 200, Speed: 16, Gusts: knots(25)}, 0, []want{
+			{"20", 16, 0, "", knots(0), 200, 200},
+			{"14", 8, 14, "R", knots(22), 140, 140},
+			{"32", -8, 14, "L", knots(22), 320, 320},
+			{"02", -16, 0, "", knots(0), 20, 20},
+		}},
+		{"declination", types.WindData{Direction: 150, Speed: 10}, 10, []want{
+			{"14", 10, 0, "", nil, 150, 140},
+			{"20", 5, 9, "L", nil, 210, 200},
+			{"02", -5, 9, "R", nil, 30, 20},
+			{"32", -10, 0, "", nil, 330, 320},
+		}},
+		{"calm", types.WindData{Calm: true}, 0, []want{
+			{"14", 0, 0, "", nil, 140, 140},
+			{"32", 0, 0, "", nil, 320, 320},
+			{"02", 0, 0, "", nil, 20, 20},
+			{"20", 0, 0, "", nil, 200, 200},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Components(ksgf, tt.wind, tt.declination)
+			if len(got) != len(tt.want) {
+				t.Fatalf("%d ends, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Runway != w.runway || g.Headwind != w.head || g.Crosswind != w.cross || g.CrossFrom != w.from ||
+					g.Heading != w.heading || g.HeadingMag != w.magHdg {
+					t.Errorf("end %d = %+v, want %+v", i, g, w)
+				}
+				if (g.GustCrosswind == nil) != (w.gust == nil) || g.GustCrosswind != nil && *g.GustCrosswind != *w.gust {
+					t.Errorf("%s gust crosswind = %v, want %v", g.Runway, g.GustCrosswind, w.gust)
+				}
+			}
+		})
+	}
+
+	if got := Components(ksgf, types.WindData{Variable: true, Speed: 4}, 0); got != nil {
+		t.Errorf("variable wind resolved onto %+v", got)
+	}
+	if got := Components(nil, types.WindData{Direction: 140, Speed: 10}, 0); got != nil {
+		t.Errorf("no runways resolved onto %+v", got)
+	}
+}
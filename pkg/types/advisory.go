@@ -0,0 +1,69 @@
+package types
+
+// AirSigmetResponse is one domestic SIGMET, convective SIGMET or AIRMET as
+// returned by the airsigmet endpoint. Altitudes are feet MSL.
+type AirSigmetResponse struct {
+	AirSigmetID   int64         `json:"airSigmetId"`
+	IcaoID        string        `json:"icaoId"`
+	AlphaChar     string        `json:"alphaChar"`
+	ReceiptTime   string        `json:"receiptTime"`
+	CreationTime  string        `json:"creationTime"`
+	ValidTimeFrom int64         `json:"validTimeFrom"`
+	ValidTimeTo   int64         `json:"validTimeTo"`
+	AirSigmetType string        `json:"airSigmetType"` // SIGMET, AIRMET, OUTLOOK
+	Hazard        string        `json:"hazard"`        // CONVECTIVE, TURB, ICE, IFR, MTN OBSCN, ASH
+	Severity      *int          `json:"severity"`
+	AltitudeLow1  *int          `json:"altitudeLow1"`
+	AltitudeLow2  *int          `json:"altitudeLow2"`
+	AltitudeHi1   *int          `json:"altitudeHi1"`
+	AltitudeHi2   *int          `json:"altitudeHi2"`
+	MovementDir   *int          `json:"movementDir"`
+	MovementSpd   *int          `json:"movementSpd"`
+	RawAirSigmet  string        `json:"rawAirSigmet"`
+	Coords        []AdvisoryPos `json:"coords"`
+}
+
+// GAirmetResponse is one G-AIRMET snapshot. Base and top are hundreds of
+// feet, or strings such as "SFC" and "FZL".
+type GAirmetResponse struct {
+	Tag          string        `json:"tag"`
+	ForecastHour int           `json:"forecastHour"`
+	IssueTime    string        `json:"issueTime"`
+	ValidTime    string        `json:"validTime"`
+	ExpireTime   string        `json:"expireTime"`
+	Product      string        `json:"product"` // SIERRA, TANGO, ZULU
+	Hazard       string        `json:"hazard"`  // IFR, MT_OBSC, TURB-HI, TURB-LO, ICE, LLWS, SFC_WND, FZLVL
+	GeometryType string        `json:"geometryType"`
+	DueTo        string        `json:"dueTo"`
+	Severity     string        `json:"severity"`
+	Base         any           `json:"base"`
+	Top          any           `json:"top"`
+	Coords       []AdvisoryPos `json:"coords"`
+}
+
+type AdvisoryPos struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// main internal struct
+type Advisory struct {
+	Product    string  `json:"product"` // SIGMET, CONVECTIVE SIGMET, AIRMET, G-AIRMET
+	Hazard     string  `json:"hazard"`  // IFR, MTN OBSC, TURB, ICE, LLWS, CONVECTIVE, ...
+	Detail     string  `json:"detail"`  // e.g. the G-AIRMET "due to"
+	Base       *Feet   `json:"base"`    // nil: surface, unless BaseFZL
+	BaseFZL    bool    `json:"baseFzl"` // based at the freezing level
+	Top        *Feet   `json:"top"`     // nil: not given
+	ValidFrom  int64   `json:"validFrom"`
+	ValidTo    int64   `json:"validTo"`
+	Inside     bool    `json:"inside"`     // the airport is within the area
+	DistanceNM float64 `json:"distanceNm"` // 0 when inside
+	Raw        string  `json:"raw"`
+}
+
+// AdvisoryArea is the set of advisories touching the selected airport
+type AdvisoryArea struct {
+	RadiusNM   float64    `json:"radiusNm"`
+	Fetched    int64      `json:"fetched"`
+	Advisories []Advisory `json:"advisories"`
+}
@@ -0,0 +1,39 @@
+package types
+
+// AFDresponse is an NWS text product as returned by api.weather.gov
+type AFDresponse struct {
+	ID              string `json:"id"`
+	WmoCollectiveID string `json:"wmoCollectiveId"`
+	IssuingOffice   string `json:"issuingOffice"`
+	IssuanceTime    string `json:"issuanceTime"`
+	ProductCode     string `json:"productCode"`
+	ProductName     string `json:"productName"`
+	ProductText     string `json:"productText"`
+}
+
+// component structs
+type AFDSection struct {
+	Name   string `json:"name"`   // e.g. SYNOPSIS, NEAR TERM, AVIATION
+	Period string `json:"period"` // "/THROUGH TONIGHT/" or "(18Z TAFS)" qualifier, if any
+	Text   string `json:"text"`
+}
+
+// main internal struct
+type AFD struct {
+	ID       string       `json:"id"`
+	Office   string       `json:"office"`
+	Issued   int64        `json:"issued"`
+	Received int64        `json:"received"` // when the daemon first saw this issuance
+	Raw      string       `json:"raw"`
+	Sections []AFDSection `json:"sections"`
+}
+
+// Section finds a section by name, e.g. "AVIATION"
+func (a AFD) Section(name string) (AFDSection, bool) {
+	for _, section := range a.Sections {
+		if section.Name == name {
+			return section, true
+		}
+	}
+	return AFDSection{}, false
+}
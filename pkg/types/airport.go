@@ -1,8 +1,36 @@
 package types
 
 type Airport struct {
-	ICAO            string `json:"icao"`
-	LastUpdateEpoch int64  `json:"last_update"`
-	Elevation       Feet   `json:"elevation"`
-	METAR           METAR  `json:"metar"`
+	ICAO            string       `json:"icao"`
+	Name            string       `json:"name"`
+	LastUpdateEpoch int64        `json:"last_update"`
+	Elevation       Feet         `json:"elevation"`
+	Lat             float64      `json:"lat"`
+	Lon             float64      `json:"lon"`
+	MagVar          *float64     `json:"magVar,omitempty"` // declination, east positive
+	METAR           METAR        `json:"metar"`
+	TAF             TAF          `json:"taf"`
+	PIREPs          PIREPArea    `json:"pireps"`
+	AFD             AFD          `json:"afd"`
+	Advisories      AdvisoryArea `json:"advisories"`
+	Runways         []RunwayWind `json:"runways,omitempty"` // best aligned first
+	Derived         *Derived     `json:"derived,omitempty"`
+
+	Departure *DepartureCheck `json:"departure,omitempty"`
+	Minimums  *MinimumsCheck  `json:"minimums,omitempty"`
+}
+
+// DepartureCheck is the TAF evaluated around a planned departure time
+type DepartureCheck struct {
+	Time       int64    `json:"time"`
+	Prevailing string   `json:"prevailing"`
+	Worst      string   `json:"worst"`
+	Warnings   []string `json:"warnings"`
+}
+
+// MinimumsCheck is the METAR, and optionally the TAF, evaluated against
+// the pilot's personal minimums
+type MinimumsCheck struct {
+	Time       int64    `json:"time"`
+	Violations []string `json:"violations"`
 }
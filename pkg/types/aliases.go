@@ -1,11 +1,12 @@
 package types
 
 type (
-	DegMag uint16 // 1-360, degrees magnetic
-	Knots  int
-	Feet   int
-	Mi     int
-	InHg   float64
+	DegMag  uint16 // 1-360, degrees magnetic
+	DegTrue uint16 // 1-360, degrees true, as METAR/TAF winds are reported
+	Knots   int
+	Feet    int
+	Mi      float64 // statute miles
+	InHg    float64
 )
 
 type Timestamp struct {
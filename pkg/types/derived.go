@@ -0,0 +1,12 @@
+package types
+
+// Derived are performance and cloud estimates worked out from the METAR
+// and field elevation
+type Derived struct {
+	PressureAlt   Feet     `json:"pressureAlt"`
+	DensityAlt    *Feet    `json:"densityAlt"`    // nil without a temperature
+	RelHumidity   *float64 `json:"relHumidity"`   // percent; nil without a temperature and dewpoint
+	CloudBase     *Feet    `json:"cloudBase"`     // convective cloud base, AGL; nil without a temperature and dewpoint
+	FreezingLevel *Feet    `json:"freezingLevel"` // MSL; the field when at or below freezing, nil without a temperature
+	HighDA        bool     `json:"highDA"`
+}
@@ -1,37 +1,42 @@
 package types
 
 type METARresponse struct { // the full data returned by the API
-	IcaoID      string   `json:"icaoId"`
-	ReceiptTime string   `json:"receiptTime"`
-	ObsTime     int64    `json:"obsTime"`
-	ReportTime  string   `json:"reportTime"`
-	MetarType   string   `json:"metarType"`
-	Temp        float64  `json:"temp"`
-	Dewp        float64  `json:"dewp"`
-	Wdir        any      `json:"wdir"`
-	Wspd        any      `json:"wspd"`
-	Visib       any      `json:"visib"`
-	Altim       float64  `json:"altim"`
-	WxString    string   `json:"wxString"`
-	QcField     int      `json:"qcField"`
-	Slp         float64  `json:"slp"`
-	PresTend    *float64 `json:"presTend"`
-	MaxT        *float64 `json:"maxT"`
-	MinT        *float64 `json:"minT"`
-	MaxT24      *float64 `json:"maxT24"`
-	MinT24      *float64 `json:"minT24"`
-	Precip      *float64 `json:"precip"`
-	Pcp3hr      *float64 `json:"pcp3hr"`
-	Pcp6hr      *float64 `json:"pcp6hr"`
-	Pcp24hr     *float64 `json:"pcp24hr"`
-	Snow        *float64 `json:"snow"`
-	VertVis     *float64 `json:"vertVis"`
-	RawOb       string   `json:"rawOb"`
-	Lat         float64  `json:"lat"`
-	Long        float64  `json:"lon"`
-	Elev        int      `json:"elev"`
-	Name        string   `json:"name"`
-	Cover       string   `json:"cover"`
+	MetarID     int64       `json:"metar_id"`
+	IcaoID      string      `json:"icaoId"`
+	ReceiptTime string      `json:"receiptTime"`
+	ObsTime     int64       `json:"obsTime"`
+	ReportTime  string      `json:"reportTime"`
+	MetarType   string      `json:"metarType"`
+	Temp        *float64    `json:"temp"`
+	Dewp        *float64    `json:"dewp"`
+	Wdir        *WindDir    `json:"wdir"`
+	Wspd        *Speed      `json:"wspd"`
+	Wgst        *Speed      `json:"wgst"`
+	Visib       *Visibility `json:"visib"`
+	Altim       float64     `json:"altim"`
+	WxString    string      `json:"wxString"`
+	QcField     int         `json:"qcField"`
+	Slp         float64     `json:"slp"`
+	PresTend    *float64    `json:"presTend"`
+	MaxT        *float64    `json:"maxT"`
+	MinT        *float64    `json:"minT"`
+	MaxT24      *float64    `json:"maxT24"`
+	MinT24      *float64    `json:"minT24"`
+	Precip      *float64    `json:"precip"`
+	Pcp3hr      *float64    `json:"pcp3hr"`
+	Pcp6hr      *float64    `json:"pcp6hr"`
+	Pcp24hr     *float64    `json:"pcp24hr"`
+	Snow        *float64    `json:"snow"`
+	VertVis     *float64    `json:"vertVis"`
+	RawOb       string      `json:"rawOb"`
+	RawTaf      string      `json:"rawTaf"`
+	MostRecent  int         `json:"mostRecent"`
+	Prior       int         `json:"prior"`
+	Lat         float64     `json:"lat"`
+	Long        float64     `json:"lon"`
+	Elev        int         `json:"elev"`
+	Name        string      `json:"name"`
+	Cover       string      `json:"cover"`
 	Clouds      []struct {
 		Cover string `json:"cover"`
 		Base  int    `json:"base"`
@@ -41,16 +46,34 @@ type METARresponse struct { // the full data returned by the API
 
 // component structs
 type WindData struct {
-	Direction DegMag `json:"direction"`
-	Speed     Knots  `json:"speed"`
-	Gusts     *Knots `json:"gusts"`
-	Variable  bool   `json:"variable"`
-	Calm      bool   `json:"calm"`
+	Direction    DegTrue        `json:"direction"`
+	DirectionMag *DegMag        `json:"directionMag,omitempty"` // once the airport's variation is known
+	Speed        Knots          `json:"speed"`
+	Gusts        *Knots         `json:"gusts"`
+	Variable     bool           `json:"variable"`
+	Calm         bool           `json:"calm"`
+	Varying      *WindVariation `json:"varying,omitempty"` // e.g. 280V340
+}
+
+type WindVariation struct {
+	From DegTrue `json:"from"`
+	To   DegTrue `json:"to"`
+}
+
+// RVR is one runway visual range group, e.g. R28L/2400V4000FT/U
+type RVR struct {
+	Runway string `json:"runway"`
+	Feet   Feet   `json:"feet"`          // lowest, when variable
+	Max    *Feet  `json:"max,omitempty"` // highest, when variable
+	Above  bool   `json:"above"`         // P: more than the value
+	Below  bool   `json:"below"`         // M: less than the value
+	Trend  string `json:"trend"`         // U(p), D(own), N(o change)
 }
 
 type CloudData struct {
 	Base     Feet   `json:"base"`
 	Coverage string `json:"coverage"`
+	Type     string `json:"type,omitempty"` // CB or TCU
 }
 
 type TempData struct {
@@ -58,18 +81,41 @@ type TempData struct {
 	Dewpoint      int     `json:"dewpoint"`
 	AmbientExact  float64 `json:"ambientExact"`
 	DewpointExact float64 `json:"dewpointExact"`
+	Source        string  `json:"source"`               // remarks (tenths), body (whole degrees) or api; empty without a temperature
+	NoTemp        bool    `json:"noTemp,omitempty"`     // not reported, e.g. ///M03 or no group; Ambient* are zero
+	NoDewpoint    bool    `json:"noDewpoint,omitempty"` // not reported, e.g. M01/; Dewpoint* are zero
 }
 
 // main internal struct
 type METAR struct {
-	Reported   Timestamp   `json:"reported"`
-	Wind       WindData    `json:"wind"`
-	Visibility Mi          `json:"visiblity"`
-	Clouds     []CloudData `json:"clouds"`
-	Temp       TempData    `json:"temp"`
-	Altimeter  InHg        `json:"altimeter"`
-	Remarks    struct {
-		Raw      []string `json:"raw"`
-		Readable []string `json:"readable"`
-	} `json:"remarks"`
+	Raw           string      `json:"raw"`
+	Type          string      `json:"type"` // METAR or SPECI
+	Corrected     bool        `json:"corrected"`
+	Auto          bool        `json:"auto"`
+	FltCat        string      `json:"fltCat"`
+	FltCatDerived string      `json:"fltCatDerived"` // from ceiling and visibility, by the configured ruleset
+	Reported      Timestamp   `json:"reported"`
+	Wind          WindData    `json:"wind"`
+	Visibility    *Visibility `json:"visiblity"` // nil when not reported
+	RVR           []RVR       `json:"rvr"`
+	WxString      string      `json:"wxString"`
+	Weather       []Weather   `json:"weather"`
+	Clouds        []CloudData `json:"clouds"`
+	VertVis       *Feet       `json:"vertVis"`
+	Temp          TempData    `json:"temp"`
+	Altimeter     InHg        `json:"altimeter"`
+	NotDecoded    string      `json:"notDecoded"`
+	Remarks       Remarks     `json:"remarks"`
+
+	// DataWarnings notes where the API's decode disagrees with the raw report
+	DataWarnings []string `json:"dataWarnings,omitempty"`
+}
+
+// Category is the API's flight category, or the derived one when the API
+// didn't give one
+func (m METAR) Category() string {
+	if m.FltCat != "" {
+		return m.FltCat
+	}
+	return m.FltCatDerived
 }
@@ -0,0 +1,105 @@
+package types
+
+// PIREPresponse is the full data returned by the API. Flight level is in
+// hundreds of feet; cloud, icing and turbulence heights are feet MSL, with
+// 0 for not reported.
+type PIREPresponse struct {
+	ReceiptTime string       `json:"receiptTime"`
+	ObsTime     int64        `json:"obsTime"`
+	QcField     int          `json:"qcField"`
+	IcaoID      string       `json:"icaoId"`
+	AcType      string       `json:"acType"`
+	Lat         float64      `json:"lat"`
+	Long        float64      `json:"lon"`
+	FltLvl      *int         `json:"fltLvl"` // hundreds of feet
+	FltLvlType  string       `json:"fltLvlType"`
+	Clouds      []PIREPcloud `json:"clouds"`
+	Visib       *Visibility  `json:"visib"`
+	WxString    string       `json:"wxString"`
+	Temp        *float64     `json:"temp"`
+	Wdir        *int         `json:"wdir"`
+	Wspd        *int         `json:"wspd"`
+	IcgBas1     *int         `json:"icgBas1"`
+	IcgTop1     *int         `json:"icgTop1"`
+	IcgInt1     string       `json:"icgInt1"`
+	IcgType1    string       `json:"icgType1"`
+	IcgBas2     *int         `json:"icgBas2"`
+	IcgTop2     *int         `json:"icgTop2"`
+	IcgInt2     string       `json:"icgInt2"`
+	IcgType2    string       `json:"icgType2"`
+	TbBas1      *int         `json:"tbBas1"`
+	TbTop1      *int         `json:"tbTop1"`
+	TbInt1      string       `json:"tbInt1"`
+	TbType1     string       `json:"tbType1"`
+	TbFreq1     string       `json:"tbFreq1"`
+	TbBas2      *int         `json:"tbBas2"`
+	TbTop2      *int         `json:"tbTop2"`
+	TbInt2      string       `json:"tbInt2"`
+	TbType2     string       `json:"tbType2"`
+	TbFreq2     string       `json:"tbFreq2"`
+	VertGust    *int         `json:"vertGust"`
+	BrkAction   string       `json:"brkAction"`
+	PirepType   string       `json:"pirepType"` // PIREP, Urgent PIREP, AIREP
+	RawOb       string       `json:"rawOb"`
+}
+
+type PIREPcloud struct {
+	Cover string `json:"cover"`
+	Base  *int   `json:"base"`
+	Top   *int   `json:"top"`
+}
+
+// component structs
+type PIREPLayer struct {
+	Intensity string `json:"intensity"`           // NEG, TRC, LGT, MOD, SEV, EXTM, or a range like LGT-MOD
+	Type      string `json:"type"`                // icing: RIME/CLR/MX, turbulence: CAT/CHOP/LLWS
+	Frequency string `json:"frequency,omitempty"` // turbulence: ISOL/OCNL/CONT
+	Base      *Feet  `json:"base"`
+	Top       *Feet  `json:"top"`
+}
+
+// PIREPFix is one point of the /OV location: a navaid or airport, with an
+// optional radial (magnetic) and distance from it
+type PIREPFix struct {
+	ID         string  `json:"id"`
+	Radial     *DegMag `json:"radial"`
+	DistanceNM *int    `json:"distanceNm"`
+}
+
+type PIREPCloud struct {
+	Coverage string `json:"coverage"`
+	Base     *Feet  `json:"base"`
+	Top      *Feet  `json:"top"`
+}
+
+// main internal struct
+type PIREP struct {
+	Raw          string       `json:"raw"`
+	Urgent       bool         `json:"urgent"`
+	ObsTime      int64        `json:"obsTime"`
+	AcType       string       `json:"acType"`
+	Lat          float64      `json:"lat"`
+	Lon          float64      `json:"lon"`
+	Location     []PIREPFix   `json:"location"`   // one fix, or a route segment
+	DistanceNM   float64      `json:"distanceNm"` // from the selected airport
+	Bearing      float64      `json:"bearing"`    // true, airport to report
+	Altitude     *Feet        `json:"altitude"`
+	AltitudeType string       `json:"altitudeType"` // e.g. DURC, DURD
+	Clouds       []PIREPCloud `json:"clouds"`
+	Visibility   *Visibility  `json:"visibility"`
+	WxString     string       `json:"wxString"`
+	Temp         *int         `json:"temp"`
+	Wind         *WindData    `json:"wind"`
+	Icing        []PIREPLayer `json:"icing"`
+	Turbulence   []PIREPLayer `json:"turbulence"`
+	Remarks      string       `json:"remarks"`
+}
+
+// PIREPArea is the set of reports around the selected airport
+type PIREPArea struct {
+	RadiusNM float64 `json:"radiusNm"`
+	MinAlt   Feet    `json:"minAlt"`
+	MaxAlt   Feet    `json:"maxAlt"`
+	Fetched  int64   `json:"fetched"`
+	Reports  []PIREP `json:"reports"`
+}
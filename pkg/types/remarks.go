@@ -0,0 +1,75 @@
+package types
+
+// Remarks is the decoded RMK section of a US METAR. Temperatures are °C,
+// pressures hPa, precipitation inches.
+type Remarks struct {
+	Raw      []string `json:"raw"` // groups not decoded
+	Readable []string `json:"readable"`
+
+	Station          string            `json:"station,omitempty"` // AO1, AO2
+	PeakWind         *PeakWind         `json:"peakWind,omitempty"`
+	WindShift        *WindShift        `json:"windShift,omitempty"`
+	WeatherEvents    []WeatherEvent    `json:"weatherEvents,omitempty"`
+	VariableCeiling  *VariableCeiling  `json:"variableCeiling,omitempty"`
+	VariableVis      *VariableVis      `json:"variableVis,omitempty"`
+	SeaLevelPressure *float64          `json:"seaLevelPressure,omitempty"`
+	PrecipHourly     *Precip           `json:"precipHourly,omitempty"`
+	Precip3or6h      *Precip           `json:"precip3or6h,omitempty"`
+	Precip24h        *Precip           `json:"precip24h,omitempty"`
+	TempExact        *float64          `json:"tempExact,omitempty"`
+	DewpointExact    *float64          `json:"dewpointExact,omitempty"`
+	Max6h            *float64          `json:"max6h,omitempty"`
+	Min6h            *float64          `json:"min6h,omitempty"`
+	Max24h           *float64          `json:"max24h,omitempty"`
+	Min24h           *float64          `json:"min24h,omitempty"`
+	PressureTendency *PressureTendency `json:"pressureTendency,omitempty"`
+	Lightning        []Lightning       `json:"lightning,omitempty"`
+	SensorOutages    []string          `json:"sensorOutages,omitempty"` // e.g. TSNO, FZRANO
+	Maintenance      bool              `json:"maintenance,omitempty"`   // $
+}
+
+type PeakWind struct {
+	Direction DegTrue `json:"direction"`
+	Speed     Knots   `json:"speed"`
+	Time      int64   `json:"time"`
+}
+
+type WindShift struct {
+	Time    int64 `json:"time"`
+	Frontal bool  `json:"frontal"` // FROPA
+}
+
+// WeatherEvent is one begin or end time, e.g. the B48 of RAB1657E07B48
+type WeatherEvent struct {
+	Weather string `json:"weather"`
+	Began   bool   `json:"began"`
+	Time    int64  `json:"time"`
+}
+
+type VariableCeiling struct {
+	Min Feet `json:"min"`
+	Max Feet `json:"max"`
+}
+
+type VariableVis struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+type Precip struct {
+	Inches float64 `json:"inches"`
+	Trace  bool    `json:"trace"`
+	Hours  int     `json:"hours"` // period the amount covers
+}
+
+// PressureTendency is the 3-hour 5appp group; Code is WMO table 0200
+type PressureTendency struct {
+	Code   int     `json:"code"`
+	Change float64 `json:"change"` // signed, hPa
+}
+
+type Lightning struct {
+	Frequency string   `json:"frequency"` // OCNL, FRQ, CONS
+	Types     []string `json:"types"`     // IC, CC, CG, CA
+	Location  string   `json:"location"`  // e.g. "DSNT W", "OHD AND NE-SE"
+}
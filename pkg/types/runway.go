@@ -0,0 +1,14 @@
+package types
+
+// RunwayWind is the wind resolved onto one runway end. Headwind is negative
+// for a tailwind; CrossFrom is the side the crosswind comes from, L or R.
+type RunwayWind struct {
+	Runway        string  `json:"runway"`
+	Heading       float64 `json:"heading"`    // degrees true
+	HeadingMag    float64 `json:"headingMag"` // degrees magnetic
+	Length        Feet    `json:"length"`
+	Headwind      Knots   `json:"headwind"`
+	Crosswind     Knots   `json:"crosswind"`
+	CrossFrom     string  `json:"crossFrom,omitempty"`
+	GustCrosswind *Knots  `json:"gustCrosswind,omitempty"`
+}
@@ -0,0 +1,115 @@
+package types
+
+type TAFresponse struct { // the full data returned by the API
+	IcaoID        string        `json:"icaoId"`
+	DbPopTime     string        `json:"dbPopTime"`
+	BulletinTime  string        `json:"bulletinTime"`
+	IssueTime     string        `json:"issueTime"`
+	ValidTimeFrom int64         `json:"validTimeFrom"`
+	ValidTimeTo   int64         `json:"validTimeTo"`
+	RawTAF        string        `json:"rawTAF"`
+	MostRecent    int           `json:"mostRecent"`
+	Remarks       string        `json:"remarks"`
+	Lat           float64       `json:"lat"`
+	Long          float64       `json:"lon"`
+	Elev          int           `json:"elev"`
+	Prior         int           `json:"prior"`
+	Name          string        `json:"name"`
+	Fcsts         []TAFforecast `json:"fcsts"`
+}
+
+type TAFforecast struct {
+	TimeFrom    int64        `json:"timeFrom"`
+	TimeTo      int64        `json:"timeTo"`
+	TimeBec     *int64       `json:"timeBec"`
+	FcstChange  *string      `json:"fcstChange"` // nil (base), FM, BECMG, TEMPO, PROB
+	Probability *int         `json:"probability"`
+	Wdir        *WindDir     `json:"wdir"`
+	Wspd        *int         `json:"wspd"`
+	Wgst        *int         `json:"wgst"`
+	WshearHgt   *int         `json:"wshearHgt"`
+	WshearDir   *int         `json:"wshearDir"`
+	WshearSpd   *int         `json:"wshearSpd"`
+	Visib       *Visibility  `json:"visib"`
+	Altim       *float64     `json:"altim"`
+	VertVis     *int         `json:"vertVis"`
+	WxString    *string      `json:"wxString"`
+	NotDecoded  *string      `json:"notDecoded"`
+	Clouds      []TAFcloud   `json:"clouds"`
+	IcgTurb     []TAFicgTurb `json:"icgTurb"`
+	Temp        []TAFtemp    `json:"temp"`
+}
+
+type TAFcloud struct {
+	Cover string  `json:"cover"`
+	Base  *int    `json:"base"`
+	Type  *string `json:"type"`
+}
+
+type TAFicgTurb struct {
+	Var       string `json:"var"` // ICE or TURB
+	Intensity int    `json:"intensity"`
+	MinAlt    int    `json:"minAlt"`
+	MaxAlt    int    `json:"maxAlt"`
+}
+
+type TAFtemp struct {
+	ValidTime int64   `json:"validTime"`
+	SfcTemp   float64 `json:"sfcTemp"`
+	MaxOrMin  string  `json:"maxOrMin"`
+}
+
+// component structs
+type WindShear struct {
+	Height    Feet    `json:"height"`
+	Direction DegTrue `json:"direction"`
+	Speed     Knots   `json:"speed"`
+}
+
+// ForecastTemp is a TX/TN group: the forecast maximum or minimum and the
+// hour it is expected
+type ForecastTemp struct {
+	Kind    string  `json:"kind"` // MAX or MIN
+	Celsius float64 `json:"celsius"`
+	At      int64   `json:"at"`
+}
+
+type IcingTurb struct {
+	Kind      string `json:"kind"` // ICE or TURB
+	Intensity int    `json:"intensity"`
+	Base      Feet   `json:"base"`
+	Top       Feet   `json:"top"`
+}
+
+// TAFPeriod is one forecast group. Pointer and slice fields are nil when
+// the group leaves that element unchanged (common for TEMPO/PROB); an empty
+// Clouds slice means sky clear.
+type TAFPeriod struct {
+	Change      string         `json:"change"`      // BASE, FM, BECMG, TEMPO, PROB
+	Probability int            `json:"probability"` // 30/40 for PROB groups
+	Temporary   bool           `json:"temporary"`   // PROB30/40 TEMPO
+	From        int64          `json:"from"`
+	To          int64          `json:"to"`
+	Becoming    int64          `json:"becoming"` // BECMG: end of transition
+	Wind        *WindData      `json:"wind"`
+	Visibility  *Visibility    `json:"visibility"`
+	Clouds      []CloudData    `json:"clouds"`
+	VertVis     *Feet          `json:"vertVis"`
+	WxString    string         `json:"wxString"`
+	WindShear   *WindShear     `json:"windShear"`
+	IcingTurb   []IcingTurb    `json:"icingTurb"`
+	Temps       []ForecastTemp `json:"temps"`
+	NotDecoded  string         `json:"notDecoded"`
+}
+
+// main internal struct
+type TAF struct {
+	ICAO      string      `json:"icao"`
+	Raw       string      `json:"raw"`
+	Amended   bool        `json:"amended"`
+	Corrected bool        `json:"corrected"`
+	Issued    int64       `json:"issued"`
+	ValidFrom int64       `json:"validFrom"`
+	ValidTo   int64       `json:"validTo"`
+	Periods   []TAFPeriod `json:"periods"`
+}
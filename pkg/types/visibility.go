@@ -0,0 +1,210 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const metersPerMile = 1609.344
+
+// Visibility is a prevailing visibility: statute miles with fractions
+// (1 3/4SM), or meters as metric stations report it
+type Visibility struct {
+	Miles  Mi   `json:"miles"`
+	Meters int  `json:"meters,omitempty"` // as reported, metric reports only
+	Plus   bool `json:"plus,omitempty"`   // more than: P6SM, "10+", 9999, CAVOK
+	Minus  bool `json:"minus,omitempty"`  // less than: M1/4SM
+
+	Directional *DirectionalVis `json:"directional,omitempty"` // e.g. 4000 1500SW
+	Variable    *VariableVis    `json:"variable,omitempty"`    // VIS 1/2V2 remark
+}
+
+// DirectionalVis is the lowest visibility toward one direction when it
+// differs markedly from the prevailing
+type DirectionalVis struct {
+	Meters    int    `json:"meters"`
+	Direction string `json:"direction"` // N, NE, E ... NW
+}
+
+// VisMiles is a visibility of miles statute miles
+func VisMiles(miles float64) Visibility {
+	return Visibility{Miles: Mi(miles)}
+}
+
+// VisMeters is a metric visibility; 9999 is 10 km or more
+func VisMeters(meters int) Visibility {
+	if meters == 9999 {
+		return Visibility{Miles: 6, Meters: meters, Plus: true}
+	}
+	return Visibility{Miles: Mi(float64(meters) / metersPerMile), Meters: meters}
+}
+
+// ParseMiles reads statute miles as the API and reports write them: "10",
+// "0.25", "1/2", "1 1/2", with an optional P/M prefix, "+" suffix or SM
+func ParseMiles(s string) (Visibility, error) {
+	text := strings.TrimSuffix(strings.TrimSpace(s), "SM")
+	var v Visibility
+	if rest, ok := strings.CutSuffix(text, "+"); ok {
+		text, v.Plus = rest, true
+	}
+	if rest, ok := strings.CutPrefix(text, "P"); ok {
+		text, v.Plus = rest, true
+	} else if rest, ok := strings.CutPrefix(text, "M"); ok {
+		text, v.Minus = rest, true
+	}
+
+	whole, frac := "", text
+	if w, f, ok := strings.Cut(text, " "); ok {
+		whole, frac = w, strings.TrimSpace(f)
+	}
+	miles := 0.0
+	if whole != "" {
+		n, err := strconv.Atoi(whole)
+		if err != nil {
+			return Visibility{}, fmt.Errorf("visibility %q: %w", s, err)
+		}
+		miles = float64(n)
+	}
+	if num, den, ok := strings.Cut(frac, "/"); ok {
+		n, errN := strconv.Atoi(num)
+		d, errD := strconv.Atoi(den)
+		if errN != nil || errD != nil || d == 0 || n > d {
+			return Visibility{}, fmt.Errorf("visibility %q: bad fraction", s)
+		}
+		miles += float64(n) / float64(d)
+	} else {
+		if whole != "" {
+			return Visibility{}, fmt.Errorf("visibility %q: bad fraction", s)
+		}
+		value, err := strconv.ParseFloat(frac, 64)
+		if err != nil || value < 0 {
+			return Visibility{}, fmt.Errorf("visibility %q: not a distance", s)
+		}
+		miles = value
+	}
+	v.Miles = Mi(miles)
+	return v, nil
+}
+
+// UnmarshalJSON takes the API's visib, a number (4, 0.25) or a string
+// ("10+", "1/2"), as well as the cached object form
+func (v *Visibility) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	switch {
+	case text == "null":
+		return nil
+	case strings.HasPrefix(text, "{"):
+		type plain Visibility
+		return json.Unmarshal(data, (*plain)(v))
+	case strings.HasPrefix(text, `"`):
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		parsed, err := ParseMiles(s)
+		if err != nil {
+			return err
+		}
+		*v = parsed
+		return nil
+	default:
+		var miles float64
+		if err := json.Unmarshal(data, &miles); err != nil {
+			return fmt.Errorf("visibility %s: %w", text, err)
+		}
+		if miles < 0 {
+			return fmt.Errorf("visibility %s: not a distance", text)
+		}
+		*v = VisMiles(miles)
+		return nil
+	}
+}
+
+// Less reports whether v is known to be below miles; a "more than"
+// visibility never is
+func (v Visibility) Less(miles float64) bool {
+	return !v.Plus && float64(v.Miles) < miles
+}
+
+// Format renders the prevailing visibility, e.g. "1 3/4SM", "6+SM",
+// "<1/4SM", or with metric "2800m", "10+km", followed by any directional
+// minimum or variable range
+func (v Visibility) Format(metric bool) string {
+	prefix, suffix := "", ""
+	switch {
+	case v.Plus:
+		suffix = "+"
+	case v.Minus:
+		prefix = "<"
+	}
+
+	var out string
+	if metric {
+		meters := float64(v.Meters)
+		switch {
+		case v.Meters == 9999:
+			meters = 10000
+		case v.Meters == 0:
+			meters = float64(v.Miles) * metersPerMile
+		}
+		value, unit := metersLabel(meters)
+		out = prefix + value + suffix + unit
+	} else {
+		out = prefix + formatMiles(float64(v.Miles)) + suffix + "SM"
+	}
+
+	if d := v.Directional; d != nil {
+		out += fmt.Sprintf(", %dm %s", d.Meters, d.Direction) // reported in meters
+	}
+	if r := v.Variable; r != nil {
+		if metric {
+			low, lowUnit := metersLabel(r.Min * metersPerMile)
+			high, highUnit := metersLabel(r.Max * metersPerMile)
+			out += fmt.Sprintf(" (%s%s-%s%s variable)", low, lowUnit, high, highUnit)
+		} else {
+			out += fmt.Sprintf(" (%s-%sSM variable)", formatMiles(r.Min), formatMiles(r.Max))
+		}
+	}
+	return out
+}
+
+func (v Visibility) String() string {
+	return v.Format(false)
+}
+
+// metersLabel rounds to the steps visibility is reported in: 50 m below
+// 800 m, 100 m below 5 km, whole kilometers above
+func metersLabel(meters float64) (value, unit string) {
+	switch {
+	case meters < 800:
+		return fmt.Sprintf("%.0f", math.Round(meters/50)*50), "m"
+	case meters < 5000:
+		return fmt.Sprintf("%.0f", math.Round(meters/100)*100), "m"
+	default:
+		return fmt.Sprintf("%.0f", math.Round(meters/1000)), "km"
+	}
+}
+
+// formatMiles writes miles as a whole number and the nearest sixteenth,
+// e.g. 1.75 as "1 3/4"
+func formatMiles(miles float64) string {
+	whole := int(miles)
+	sixteenths := int(math.Round((miles - float64(whole)) * 16))
+	if sixteenths == 16 {
+		whole, sixteenths = whole+1, 0
+	}
+	if sixteenths == 0 {
+		return strconv.Itoa(whole)
+	}
+	num, den := sixteenths, 16
+	for num%2 == 0 {
+		num, den = num/2, den/2
+	}
+	if whole == 0 {
+		return fmt.Sprintf("%d/%d", num, den)
+	}
+	return fmt.Sprintf("%d %d/%d", whole, num, den)
+}
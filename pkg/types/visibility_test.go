@@ -0,0 +1,113 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVisibilityUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want Visibility
+	}{
+		{`"10+"`, Visibility{Miles: 10, Plus: true}},
+		{`4`, Visibility{Miles: 4}},
+		{`0.25`, Visibility{Miles: 0.25}},
+		{`"1/2"`, Visibility{Miles: 0.5}},
+		{`"1 1/2"`, Visibility{Miles: 1.5}},
+		{`"M1/4"`, Visibility{Miles: 0.25, Minus: true}},
+		{`"P6"`, Visibility{Miles: 6, Plus: true}},
+		{`"3SM"`, Visibility{Miles: 3}},
+		{`null`, Visibility{}},
+		{`{"miles":0.5,"minus":true}`, Visibility{Miles: 0.5, Minus: true}},
+	}
+	for _, tt := range tests {
+		var got Visibility
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+			t.Errorf("%s: %v", tt.json, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: %+v, want %+v", tt.json, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`"garbage"`, `"3/0"`, `"1 1.5"`, `"5/4"`, `-1`, `true`} {
+		var v Visibility
+		if err := json.Unmarshal([]byte(bad), &v); err == nil {
+			t.Errorf("%s decoded as %+v", bad, v)
+		}
+	}
+}
+
+func TestVisibilityFormat(t *testing.T) {
+	tests := []struct {
+		vis           Visibility
+		miles, metric string
+	}{
+		{VisMiles(10), "10SM", "16km"},
+		{VisMiles(1.75), "1 3/4SM", "2800m"},
+		{VisMiles(0.5), "1/2SM", "800m"},
+		{Visibility{Miles: 0.25, Minus: true}, "<1/4SM", "<400m"},
+		{Visibility{Miles: 6, Plus: true}, "6+SM", "10+km"},
+		{VisMeters(9999), "6+SM", "10+km"},
+		{VisMeters(2800), "1 3/4SM", "2800m"},
+		{VisMeters(600), "3/8SM", "600m"},
+		{Visibility{Miles: 2.5, Meters: 4000, Directional: &DirectionalVis{Meters: 1500, Direction: "SW"}},
+			"2 1/2SM, 1500m SW", "4000m, 1500m SW"},
+		{Visibility{Miles: 1, Variable: &VariableVis{Min: 0.5, Max: 2}},
+			"1SM (1/2-2SM variable)", "1600m (800m-3200m variable)"},
+	}
+	for _, tt := range tests {
+		if got := tt.vis.Format(false); got != tt.miles {
+			t.Errorf("%+v: Format(false) = %q, want %q", tt.vis, got, tt.miles)
+		}
+		if got := tt.vis.Format(true); got != tt.metric {
+			t.Errorf("%+v: Format(true) = %q, want %q", tt.vis, got, tt.metric)
+		}
+	}
+}
+
+func TestVisibilityRoundTrip(t *testing.T) {
+	for _, s := range []string{"10+", "1/2", "1 1/2", "M1/4", "P6", "2 3/4"} {
+		v, err := ParseMiles(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back Visibility
+		if err := json.Unmarshal(data, &back); err != nil || back != v {
+			t.Errorf("%s: %s came back as %+v (%v)", s, data, back, err)
+		}
+		// plain miles are formatted the way they're read
+		if v.Plus || v.Minus {
+			continue
+		}
+		if again, err := ParseMiles(v.Format(false)); err != nil || again != v {
+			t.Errorf("%s: Format %q parsed as %+v (%v)", s, v.Format(false), again, err)
+		}
+	}
+}
+
+func TestVisibilityLess(t *testing.T) {
+	tests := []struct {
+		vis   Visibility
+		miles float64
+		want  bool
+	}{
+		{VisMiles(2), 3, true},
+		{VisMiles(3), 3, false},
+		{VisMiles(5), 3, false},
+		{Visibility{Miles: 0.25, Minus: true}, 0.5, true},
+		{Visibility{Miles: 6, Plus: true}, 10, false}, // more than 6 may be 10
+		{VisMeters(9999), 7, false},
+	}
+	for _, tt := range tests {
+		if got := tt.vis.Less(tt.miles); got != tt.want {
+			t.Errorf("%+v.Less(%v) = %v, want %v", tt.vis, tt.miles, got, tt.want)
+		}
+	}
+}
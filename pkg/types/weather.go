@@ -0,0 +1,17 @@
+package types
+
+// Weather is one decoded present-weather group, e.g. "-FZRA" or "VCTS"
+type Weather struct {
+	Raw        string   `json:"raw"`
+	Intensity  string   `json:"intensity"` // light, moderate, heavy
+	Vicinity   bool     `json:"vicinity"`
+	Descriptor string   `json:"descriptor"` // MI, PR, BC, DR, BL, SH, TS, FZ
+	Phenomena  []string `json:"phenomena"`  // RA, SN, BR, ...
+	Text       string   `json:"text"`       // "light freezing rain"
+
+	Precipitation  bool `json:"precipitation"`
+	Obscuration    bool `json:"obscuration"`
+	Thunderstorm   bool `json:"thunderstorm"`
+	FreezingPrecip bool `json:"freezingPrecip"`
+	Severe         bool `json:"severe"` // hail, squalls, funnel clouds, dust/sand storms, ash
+}
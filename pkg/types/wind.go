@@ -0,0 +1,68 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WindDir is the API's wdir: degrees true, or "VRB" for a variable wind
+type WindDir struct {
+	Degrees  DegTrue
+	Variable bool
+}
+
+func (d *WindDir) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if text == "null" {
+		return nil
+	}
+	if s, err := strconv.Unquote(text); err == nil {
+		if s == "VRB" {
+			*d = WindDir{Variable: true}
+			return nil
+		}
+		text = s
+	}
+	deg, err := strconv.Atoi(text)
+	if err != nil || deg < 0 || deg > 360 {
+		return fmt.Errorf("wdir %s: not a direction", string(data))
+	}
+	*d = WindDir{Degrees: DegTrue(deg)}
+	return nil
+}
+
+func (d WindDir) MarshalJSON() ([]byte, error) {
+	if d.Variable {
+		return []byte(`"VRB"`), nil
+	}
+	return json.Marshal(int(d.Degrees))
+}
+
+func (d WindDir) String() string {
+	if d.Variable {
+		return "VRB"
+	}
+	return fmt.Sprintf("%03d", d.Degrees)
+}
+
+// Speed is the API's wspd/wgst in knots, a whole number that is
+// occasionally sent as a string
+type Speed Knots
+
+func (s *Speed) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	knots, err := strconv.ParseFloat(text, 64)
+	if err != nil || knots < 0 || knots != float64(int(knots)) {
+		return fmt.Errorf("wspd %s: not a speed", string(data))
+	}
+	*s = Speed(knots)
+	return nil
+}